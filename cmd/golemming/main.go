@@ -2,11 +2,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 	"github.com/thesimpledev/golemming/internal/action"
 	"github.com/thesimpledev/golemming/internal/agent"
 	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/internal/session"
 	"github.com/thesimpledev/golemming/internal/ui"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
@@ -22,12 +27,31 @@ import (
 var Version = "dev"
 
 func main() {
+	// Subcommands; a report only reads files and needs no desktop
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			checkPlatform()
+			runReplay(os.Args[2:])
+			return
+		case "report":
+			runReport(os.Args[2:])
+			return
+		}
+	}
+
 	checkPlatform()
 
 	// Parse flags
 	goal := flag.String("goal", "", "Goal to accomplish (runs in headless mode)")
 	headless := flag.Bool("headless", false, "Run in headless mode without TUI")
 	maxIter := flag.Int("max-iterations", 100, "Maximum number of iterations")
+	script := flag.String("script", "", "Replay actions from a JSON script instead of calling a model")
+	record := flag.Bool("record", false, "Record the session (screenshots, prompts, actions) to disk")
+	verify := flag.String("verify", "", "Have a verifier review the work: post_task or per_step")
+	verifyScript := flag.String("verify-script", "", "Replay the verifier's verdicts from a JSON script")
+	approve := flag.String("approve", "", "Ask before running actions: always, file_write or keys")
+	approveKeys := flag.String("approve-keys", "", "Regular expression of key names to ask about (implies -approve keys)")
 	showVersion := flag.Bool("version", false, "Show version and exit")
 	flag.Parse()
 
@@ -36,19 +60,51 @@ func main() {
 		return
 	}
 
+	// Set up the native input backend
+	backend, err := input.NewDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Command line settings applied on top of the loaded config
+	overrides := func(cfg *config.Config) {
+		if *script != "" {
+			cfg.Provider = config.ProviderScript
+			cfg.ScriptPath = *script
+		}
+		if *record {
+			cfg.RecordSessions = true
+		}
+		if *verify != "" {
+			cfg.Verify = *verify
+		}
+		if *verifyScript != "" {
+			cfg.VerifyProvider = config.ProviderScript
+			cfg.VerifyScriptPath = *verifyScript
+		}
+		if *approve != "" {
+			cfg.Approval = *approve
+		}
+		if *approveKeys != "" {
+			cfg.Approval = config.ApprovalKeys
+			cfg.ApprovalKeyPattern = *approveKeys
+		}
+	}
+
 	// If goal is provided, run in headless mode
 	if *goal != "" || *headless {
 		if *goal == "" {
 			fmt.Fprintln(os.Stderr, "Error: -goal is required in headless mode")
 			os.Exit(1)
 		}
-		runHeadless(*goal, *maxIter)
+		runHeadless(*goal, *maxIter, backend, overrides)
 		return
 	}
 
 	// Run interactive TUI
 	p := tea.NewProgram(
-		ui.New(),
+		ui.New(backend, overrides),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
@@ -60,10 +116,11 @@ func main() {
 }
 
 // runHeadless runs the agent in headless mode (for scripting/automation).
-func runHeadless(goal string, maxIter int) {
+func runHeadless(goal string, maxIter int, backend input.Backend, overrides func(*config.Config)) {
 	// Load config
-	cfg, err := config.Load()
-	if err != nil {
+	cfg := config.Read()
+	overrides(cfg)
+	if err := cfg.Validate(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -73,7 +130,11 @@ func runHeadless(goal string, maxIter int) {
 	}
 
 	// Create agent
-	ag := agent.New(cfg)
+	ag, err := agent.New(cfg, backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Set up action callback for logging
 	ag.OnAction(func(act *protocol.Action, result *action.Result) {
@@ -82,7 +143,30 @@ func runHeadless(goal string, maxIter int) {
 		if !result.Success {
 			status = "ERROR: " + result.Error
 		}
-		fmt.Printf("[%s] %s: %s [%s]\n", timestamp, act.Type, formatAction(act), status)
+		fmt.Printf("[%s] %s: %s [%s]\n", timestamp, act.Type, action.Describe(act), status)
+	})
+
+	ag.OnScreenshot(func(info agent.ScreenshotInfo) {
+		timestamp := time.Now().Format("15:04:05")
+		fmt.Printf("[%s] screenshot: %dx%d %s, %.1f KB\n", timestamp, info.Width, info.Height, info.MediaType, float64(info.Size)/1024)
+	})
+
+	ag.OnRetry(func(event llm.RetryEvent) {
+		timestamp := time.Now().Format("15:04:05")
+		fmt.Printf("[%s] retrying (%d/%d) in %s: %v\n", timestamp, event.Retry, event.MaxRetries, event.Delay.Round(time.Millisecond), event.Err)
+	})
+
+	ag.OnVerdict(func(verdict *protocol.Verdict) {
+		timestamp := time.Now().Format("15:04:05")
+		fmt.Printf("[%s] verifier: %s: %s\n", timestamp, verdict.Type, action.DescribeVerdict(verdict))
+	})
+
+	// Approval prompts read the answers from stdin; notes are marked
+	// with notePrefix while approvals are on
+	approvals := cfg.Approval != config.ApprovalNever
+	lines := routeNotes(readLines(os.Stdin), ag, approvals)
+	ag.OnApproval(func(ctx context.Context, req agent.ApprovalRequest) (agent.Decision, error) {
+		return promptApproval(ctx, req, lines)
 	})
 
 	// Set up signal handling for graceful shutdown
@@ -99,13 +183,21 @@ func runHeadless(goal string, maxIter int) {
 
 	// Run agent
 	fmt.Printf("Starting agent with goal: %s\n", goal)
-	fmt.Println("Press Ctrl+C to stop")
+	if approvals {
+		fmt.Printf("Press Ctrl+C to stop, or type a line starting with %s to send the agent a note\n", notePrefix)
+	} else {
+		fmt.Println("Press Ctrl+C to stop, or type a line to send the agent a note")
+	}
 	fmt.Println("---")
 
 	err = ag.Run(ctx, goal)
 
 	fmt.Println("---")
 
+	if dir := ag.SessionDir(); dir != "" {
+		fmt.Printf("Session recorded to: %s\n", dir)
+	}
+
 	// Print result
 	state := ag.State()
 	switch state {
@@ -126,37 +218,114 @@ func runHeadless(goal string, maxIter int) {
 	// Print history summary
 	history := ag.History()
 	fmt.Printf("\nTotal actions: %d\n", history.Len())
+	if rejected := len(history.Rejections()); rejected > 0 {
+		fmt.Printf("Rejected responses: %d\n", rejected)
+	}
+	if notes := len(history.Notes()); notes > 0 {
+		fmt.Printf("Operator notes: %d\n", notes)
+	}
 }
 
-func formatAction(act *protocol.Action) string {
-	switch act.Type {
-	case protocol.ActionClick:
-		btn := act.Button
-		if btn == "" {
-			btn = "left"
+// runReplay re-executes a recorded session without the LLM.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	delay := fs.Duration("delay", 500*time.Millisecond, "Extra pause after each action")
+	compare := fs.Bool("compare", false, "Compare the screen with the recorded screenshot before each step")
+	tolerance := fs.Float64("tolerance", 0.1, "Share of pixels that may differ before a step counts as diverged")
+	keepGoing := fs.Bool("keep-going", false, "Continue after a divergence instead of stopping")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: golemming replay [flags] <session-dir>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	dir := fs.Arg(0)
+
+	backend, err := input.NewDefault()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg := config.Read()
+	opts := session.ReplayOptions{
+		Delay:                *delay,
+		Settle:               cfg.StabilizationDelay(),
+		SettleTimeout:        cfg.StabilizationTimeout(),
+		Compare:              *compare,
+		Tolerance:            *tolerance,
+		KeepGoing:            *keepGoing,
+		RequireAbsolutePaths: cfg.RequireAbsolutePaths,
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Printf("Replaying %s\n", dir)
+	fmt.Println("Press Ctrl+C to stop")
+	fmt.Println("---")
+
+	err = session.Replay(ctx, dir, backend, opts, func(event session.ReplayEvent) {
+		timestamp := time.Now().Format("15:04:05")
+		detail := ""
+		if event.Action != nil {
+			detail = fmt.Sprintf("%s: %s", event.Action.Type, action.Describe(event.Action))
 		}
-		dbl := ""
-		if act.Double {
-			dbl = " double"
+
+		var status string
+		switch {
+		case event.Diverged:
+			status = "DIVERGED: " + event.Reason
+		case event.Result == nil:
+			status = "skipped: " + event.Reason
+		default:
+			status = "OK"
 		}
-		return fmt.Sprintf("%s%s at (%d, %d)", btn, dbl, act.X, act.Y)
-	case protocol.ActionType_:
-		text := act.Text
-		if len(text) > 30 {
-			text = text[:30] + "..."
+		if event.Diff >= 0 {
+			status += fmt.Sprintf(", %.1f%% differs", event.Diff*100)
 		}
-		return fmt.Sprintf("%q", text)
-	case protocol.ActionKey:
-		return act.Key
-	case protocol.ActionScroll:
-		return fmt.Sprintf("%s %d", act.Direction, act.Amount)
-	case protocol.ActionFileRead:
-		return act.Path
-	case protocol.ActionFileWrite:
-		return act.Path
-	case protocol.ActionWait:
-		return fmt.Sprintf("%dms", act.Ms)
-	default:
-		return ""
+		fmt.Printf("[%s] step %d %s [%s]\n", timestamp, event.Step, detail, status)
+	})
+
+	fmt.Println("---")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Replay finished")
+}
+
+// runReport writes an HTML report for a recorded session.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	output := fs.String("o", "", "Output file (default: report.html in the session directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: golemming report [flags] <session-dir>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	dir := fs.Arg(0)
+
+	path := *output
+	if path == "" {
+		path = filepath.Join(dir, session.ReportFile)
+	}
+
+	var buf bytes.Buffer
+	if err := session.WriteReport(&buf, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write report: %v\n", err)
+		os.Exit(1)
 	}
+	fmt.Printf("Report written to: %s\n", path)
 }
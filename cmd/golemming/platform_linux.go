@@ -0,0 +1,16 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func checkPlatform() {
+	if os.Getenv("DISPLAY") == "" {
+		fmt.Fprintln(os.Stderr, "Error: DISPLAY is not set.")
+		fmt.Fprintln(os.Stderr, "On Linux GoLemming requires an X11 display (a desktop session or Xvfb).")
+		os.Exit(1)
+	}
+}
@@ -1,4 +1,4 @@
-//go:build !windows
+//go:build !windows && !linux
 
 package main
 
@@ -8,7 +8,7 @@ import (
 )
 
 func checkPlatform() {
-	fmt.Fprintln(os.Stderr, "Error: GoLemming only runs on Windows.")
-	fmt.Fprintln(os.Stderr, "It requires Windows APIs for screen capture and input simulation.")
+	fmt.Fprintln(os.Stderr, "Error: GoLemming only runs on Windows and Linux (X11).")
+	fmt.Fprintln(os.Stderr, "It requires native APIs for screen capture and input simulation.")
 	os.Exit(1)
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/agent"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// readLines reads r line by line in the background. The channel is closed
+// at end of input. Headless mode reads stdin once, through this, so that
+// every prompt shares the same reader.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// notePrefix marks a stdin line as an operator note while approval
+// prompts read the other lines.
+const notePrefix = ">"
+
+// routeNotes sends operator notes read from lines to the agent and returns
+// the remaining lines, in order, for approval prompts. With approvals on, a
+// note starts with notePrefix and every other line is queued until a prompt
+// reads it, so answers piped in ahead of time reach their prompts. With
+// approvals off every non-empty line is a note. The returned channel is
+// closed once lines is and the queue is drained.
+func routeNotes(lines <-chan string, ag *agent.Agent, approvals bool) <-chan string {
+	answers := make(chan string)
+	go func() {
+		defer close(answers)
+		var queued []string
+		for lines != nil || len(queued) > 0 {
+			var out chan string
+			var next string
+			if len(queued) > 0 {
+				out, next = answers, queued[0]
+			}
+
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					lines = nil
+					continue
+				}
+				text := strings.TrimSpace(line)
+				note, isNote := strings.CutPrefix(text, notePrefix)
+				if !isNote && approvals {
+					queued = append(queued, line)
+					continue
+				}
+				if note = strings.TrimSpace(note); note == "" {
+					continue
+				}
+				ag.AddNote(note)
+				timestamp := time.Now().Format("15:04:05")
+				fmt.Printf("[%s] note queued for the next step: %s\n", timestamp, note)
+			case out <- next:
+				queued = queued[1:]
+			}
+		}
+	}()
+	return answers
+}
+
+// promptApproval asks on the terminal whether to run a proposed action and
+// reads the answer from lines:
+//
+//	y (or empty)    approve
+//	n [feedback]    reject, telling the model why
+//	e {json}        edit, e.g. e {"x": 420}
+func promptApproval(ctx context.Context, req agent.ApprovalRequest, lines <-chan string) (agent.Decision, error) {
+	timestamp := time.Now().Format("15:04:05")
+	fmt.Printf("[%s] approve %s: %s", timestamp, req.Action.Type, action.Describe(req.Action))
+	if req.HasTarget {
+		fmt.Printf(" (screen %d, %d)", req.Screen.X, req.Screen.Y)
+	}
+	if req.Action.Type == protocol.ActionFileWrite {
+		fmt.Printf(", %d bytes", len(req.Action.Content))
+	}
+	fmt.Println()
+
+	for {
+		fmt.Print("  [y]es / [n]o <feedback> / [e]dit <json>: ")
+
+		var line string
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return agent.Decision{}, ctx.Err()
+		case l, ok := <-lines:
+			if !ok {
+				return agent.Decision{}, fmt.Errorf("stdin closed while waiting for approval")
+			}
+			line = strings.TrimSpace(l)
+		}
+
+		answer, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch strings.ToLower(answer) {
+		case "", "y", "yes":
+			return agent.Decision{Approved: true}, nil
+		case "n", "no":
+			return agent.Decision{Feedback: rest}, nil
+		case "e", "edit":
+			edited, err := agent.EditAction(req.Action, rest)
+			if err != nil {
+				fmt.Printf("  %v\n", err)
+				continue
+			}
+			return agent.Decision{Approved: true, Action: edited}, nil
+		default:
+			fmt.Println("  Please answer y, n or e.")
+		}
+	}
+}
@@ -1,27 +1,56 @@
 package action
 
 import (
+	"fmt"
+	"image"
 	"strings"
 	"time"
 
+	"github.com/thesimpledev/golemming/internal/capture"
 	"github.com/thesimpledev/golemming/internal/input"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
+// Screen is the view actions are taken against: coordinates refer to its
+// last frame, and display and zoom actions change what it captures next.
+// *capture.Capturer implements it.
+type Screen interface {
+	Last() *capture.Frame
+	SetDisplay(display int) error
+	Zoom(region image.Rectangle) error
+	ResetZoom()
+}
+
 // Executor handles action execution.
 type Executor struct {
+	input                input.Backend
+	screen               Screen
 	requireAbsolutePaths bool
 }
 
-// NewExecutor creates a new action executor.
-func NewExecutor(requireAbsolutePaths bool) *Executor {
+// NewExecutor creates a new action executor that sends input through backend.
+// Coordinates are taken relative to the last frame captured by screen.
+func NewExecutor(backend input.Backend, screen Screen, requireAbsolutePaths bool) *Executor {
 	return &Executor{
+		input:                backend,
+		screen:               screen,
 		requireAbsolutePaths: requireAbsolutePaths,
 	}
 }
 
-// Execute executes an action and returns the result.
+// Execute executes an action and returns the result. A display set on the
+// action is switched to after the action itself succeeds.
 func (e *Executor) Execute(action *protocol.Action) *Result {
+	result := e.execute(action)
+	if result.Success && action.Display != 0 {
+		if err := e.screen.SetDisplay(action.Display); err != nil {
+			return &Result{Success: false, Error: err.Error()}
+		}
+	}
+	return result
+}
+
+func (e *Executor) execute(action *protocol.Action) *Result {
 	switch action.Type {
 	case protocol.ActionClick:
 		return e.executeClick(action)
@@ -37,6 +66,11 @@ func (e *Executor) Execute(action *protocol.Action) *Result {
 		return e.executeFileWrite(action)
 	case protocol.ActionWait:
 		return e.executeWait(action)
+	case protocol.ActionZoom:
+		return e.executeZoom(action)
+	case protocol.ActionZoomReset:
+		e.screen.ResetZoom()
+		return &Result{Success: true}
 	case protocol.ActionDone, protocol.ActionFailed:
 		// These are handled by the agent, not the executor
 		return &Result{Success: true}
@@ -46,7 +80,18 @@ func (e *Executor) Execute(action *protocol.Action) *Result {
 }
 
 func (e *Executor) executeClick(action *protocol.Action) *Result {
-	input.Move(action.X, action.Y)
+	x, y := action.X, action.Y
+	if action.Cell != "" {
+		var err error
+		if x, y, err = e.cell(action.Cell); err != nil {
+			return &Result{Success: false, Error: err.Error()}
+		}
+	}
+
+	x, y = e.toScreen(x, y)
+	if err := e.input.Move(x, y); err != nil {
+		return inputError(err)
+	}
 	time.Sleep(50 * time.Millisecond) // Small delay for cursor to settle
 
 	button := action.Button
@@ -54,12 +99,16 @@ func (e *Executor) executeClick(action *protocol.Action) *Result {
 		button = "left"
 	}
 
-	input.Click(button, action.Double)
+	if err := e.input.Click(button, action.Double); err != nil {
+		return inputError(err)
+	}
 	return &Result{Success: true}
 }
 
 func (e *Executor) executeType(action *protocol.Action) *Result {
-	input.TypeText(action.Text)
+	if err := e.input.TypeText(action.Text); err != nil {
+		return inputError(err)
+	}
 	return &Result{Success: true}
 }
 
@@ -67,10 +116,14 @@ func (e *Executor) executeKey(action *protocol.Action) *Result {
 	key := action.Key
 
 	// Check if it's a combo (contains +)
+	var err error
 	if strings.Contains(key, "+") {
-		input.KeyCombo(key)
+		err = e.input.KeyCombo(key)
 	} else {
-		input.KeyPress(key)
+		err = e.input.KeyPress(key)
+	}
+	if err != nil {
+		return inputError(err)
 	}
 
 	return &Result{Success: true}
@@ -82,7 +135,9 @@ func (e *Executor) executeScroll(action *protocol.Action) *Result {
 		amount = 3
 	}
 
-	input.ScrollDir(amount, action.Direction)
+	if err := e.input.ScrollDir(amount, action.Direction); err != nil {
+		return inputError(err)
+	}
 	return &Result{Success: true}
 }
 
@@ -110,3 +165,34 @@ func (e *Executor) executeWait(action *protocol.Action) *Result {
 	time.Sleep(time.Duration(ms) * time.Millisecond)
 	return &Result{Success: true}
 }
+
+func (e *Executor) executeZoom(action *protocol.Action) *Result {
+	x0, y0 := e.toScreen(action.X, action.Y)
+	x1, y1 := e.toScreen(action.X+action.Width, action.Y+action.Height)
+	if err := e.screen.Zoom(image.Rect(x0, y0, x1, y1)); err != nil {
+		return &Result{Success: false, Error: err.Error()}
+	}
+	return &Result{Success: true}
+}
+
+// cell resolves a grid cell reference to screenshot coordinates.
+func (e *Executor) cell(ref string) (int, int, error) {
+	frame := e.screen.Last()
+	if frame == nil || frame.Grid == nil {
+		return 0, 0, fmt.Errorf("cell %s given but the grid overlay is off; use x and y", ref)
+	}
+	return frame.Grid.Cell(ref)
+}
+
+// toScreen maps screenshot coordinates to global screen coordinates using
+// the frame the model was looking at.
+func (e *Executor) toScreen(x, y int) (int, int) {
+	if frame := e.screen.Last(); frame != nil {
+		return frame.ToScreen(x, y)
+	}
+	return x, y
+}
+
+func inputError(err error) *Result {
+	return &Result{Success: false, Error: "input failed: " + err.Error()}
+}
@@ -0,0 +1,143 @@
+package action
+
+import (
+	"errors"
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// fakeScreen is a Screen showing a fixed frame.
+type fakeScreen struct {
+	frame   *capture.Frame
+	display int
+	zoom    image.Rectangle
+}
+
+func (s *fakeScreen) Last() *capture.Frame { return s.frame }
+
+func (s *fakeScreen) SetDisplay(display int) error {
+	s.display = display
+	return nil
+}
+
+func (s *fakeScreen) Zoom(region image.Rectangle) error {
+	s.zoom = region
+	return nil
+}
+
+func (s *fakeScreen) ResetZoom() { s.zoom = image.Rectangle{} }
+
+// secondDisplay is a frame of a 1280x720 display right of a 1920 wide
+// primary, downscaled by half.
+func secondDisplay() *capture.Frame {
+	return &capture.Frame{
+		Image:   image.NewRGBA(image.Rect(0, 0, 640, 360)),
+		Display: 2,
+		Count:   2,
+		Bounds:  image.Rect(1920, 0, 3200, 720),
+		Scale:   0.5,
+	}
+}
+
+func TestExecuteInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		action protocol.Action
+		frame  *capture.Frame
+		want   []input.Event
+	}{
+		{
+			name:   "click without frame",
+			action: protocol.Action{Type: protocol.ActionClick, X: 100, Y: 200},
+			want: []input.Event{
+				{Kind: "move", X: 100, Y: 200},
+				{Kind: "click", Button: "left"},
+			},
+		},
+		{
+			name:   "click maps to screen",
+			action: protocol.Action{Type: protocol.ActionClick, X: 100, Y: 50, Button: "right", Double: true},
+			frame:  secondDisplay(),
+			want: []input.Event{
+				{Kind: "move", X: 2121, Y: 101},
+				{Kind: "click", Button: "right", Double: true},
+			},
+		},
+		{
+			name:   "type",
+			action: protocol.Action{Type: protocol.ActionType_, Text: "hello, world"},
+			want:   []input.Event{{Kind: "type", Text: "hello, world"}},
+		},
+		{
+			name:   "key",
+			action: protocol.Action{Type: protocol.ActionKey, Key: "enter"},
+			want:   []input.Event{{Kind: "key_press", Key: "enter"}},
+		},
+		{
+			name:   "key combo",
+			action: protocol.Action{Type: protocol.ActionKey, Key: "ctrl+shift+s"},
+			want:   []input.Event{{Kind: "key_combo", Key: "ctrl+shift+s"}},
+		},
+		{
+			name:   "scroll",
+			action: protocol.Action{Type: protocol.ActionScroll, Direction: "down", Amount: 5},
+			want:   []input.Event{{Kind: "scroll", Amount: 5, Direction: "down"}},
+		},
+		{
+			name:   "scroll default amount",
+			action: protocol.Action{Type: protocol.ActionScroll, Direction: "up"},
+			want:   []input.Event{{Kind: "scroll", Amount: 3, Direction: "up"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := input.NewRecorder()
+			e := NewExecutor(rec, &fakeScreen{frame: tt.frame}, true)
+
+			result := e.Execute(&tt.action)
+			if !result.Success {
+				t.Fatalf("Execute failed: %s", result.Error)
+			}
+			if got := rec.Events(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteInputError(t *testing.T) {
+	rec := input.NewRecorder()
+	rec.Err = errors.New("no display")
+	e := NewExecutor(rec, &fakeScreen{}, true)
+
+	result := e.Execute(&protocol.Action{Type: protocol.ActionKey, Key: "enter"})
+	if result.Success {
+		t.Fatal("Execute succeeded despite the input error")
+	}
+	if want := "input failed: no display"; result.Error != want {
+		t.Errorf("error = %q, want %q", result.Error, want)
+	}
+}
+
+func TestExecuteSwitchesDisplay(t *testing.T) {
+	rec := input.NewRecorder()
+	screen := &fakeScreen{}
+	e := NewExecutor(rec, screen, true)
+
+	result := e.Execute(&protocol.Action{Type: protocol.ActionWait, Ms: 1, Display: 2})
+	if !result.Success {
+		t.Fatalf("Execute failed: %s", result.Error)
+	}
+	if screen.display != 2 {
+		t.Errorf("display = %d, want 2", screen.display)
+	}
+	if events := rec.Events(); len(events) != 0 {
+		t.Errorf("wait sent input: %+v", events)
+	}
+}
@@ -1,11 +1,20 @@
 package action
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"unicode/utf8"
 )
 
+// MaxFileReadBytes is the largest amount of file content passed back to the
+// model for a single file_read. Longer files are truncated with a marker.
+const MaxFileReadBytes = 16 * 1024
+
+// binarySniffLen is how much of a file is inspected for NUL bytes.
+const binarySniffLen = 8000
+
 // ReadFile reads the contents of a file.
 func ReadFile(path string, requireAbsolute bool) (string, error) {
 	if requireAbsolute && !filepath.IsAbs(path) {
@@ -38,3 +47,34 @@ func WriteFile(path, content string, requireAbsolute bool) error {
 
 	return nil
 }
+
+// FileReadOutput prepares file contents for the model: binary files are
+// replaced by a short note and long files are truncated to MaxFileReadBytes.
+func FileReadOutput(content string) string {
+	if isBinary(content) {
+		return fmt.Sprintf("[binary file, %d bytes; contents not shown]", len(content))
+	}
+	if len(content) <= MaxFileReadBytes {
+		return content
+	}
+
+	// Cut on a rune boundary so we never hand the model half a character
+	cut := MaxFileReadBytes
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	return content[:cut] + fmt.Sprintf("\n[... truncated: showing first %d of %d bytes]", cut, len(content))
+}
+
+// isBinary reports whether content looks like binary data rather than text.
+func isBinary(content string) bool {
+	sniff := content
+	if len(sniff) > binarySniffLen {
+		sniff = sniff[:binarySniffLen]
+		// Don't let a multi-byte rune split by the cut count as invalid
+		for len(sniff) > 0 && !utf8.RuneStart(content[len(sniff)]) {
+			sniff = sniff[:len(sniff)-1]
+		}
+	}
+	return bytes.IndexByte([]byte(sniff), 0) >= 0 || !utf8.ValidString(sniff)
+}
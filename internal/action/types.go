@@ -2,15 +2,18 @@
 package action
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/thesimpledev/golemming/internal/llm"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
 // Result represents the result of executing an action.
 type Result struct {
-	Success bool
-	Error   string
-	Data    string // For file_read, contains file contents
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Data    string `json:"data,omitempty"` // For file_read, contains file contents
 }
 
 // ToHistoryEntry converts an action and result to a history entry.
@@ -31,10 +34,73 @@ func ToHistoryEntry(action *protocol.Action, result *Result) llm.HistoryEntry {
 			Ms:        action.Ms,
 			Summary:   action.Summary,
 			Reason:    action.Reason,
+			Cell:      action.Cell,
+			Width:     action.Width,
+			Height:    action.Height,
+			Display:   action.Display,
 		},
 	}
 	if !result.Success {
 		entry.Error = result.Error
+	} else if action.Type == protocol.ActionFileRead {
+		entry.Output = FileReadOutput(result.Data)
 	}
 	return entry
 }
+
+// Describe returns a short, human readable summary of an action's
+// arguments, such as "left at (100, 200)", for display next to its type.
+func Describe(act *protocol.Action) string {
+	switch act.Type {
+	case protocol.ActionClick:
+		btn := act.Button
+		if btn == "" {
+			btn = "left"
+		}
+		if act.Double {
+			btn = "double " + btn
+		}
+		if act.Cell != "" {
+			return fmt.Sprintf("%s at cell %s", btn, act.Cell)
+		}
+		return fmt.Sprintf("%s at (%d, %d)", btn, act.X, act.Y)
+	case protocol.ActionType_:
+		text := act.Text
+		if len(text) > 30 {
+			text = text[:30] + "..."
+		}
+		return fmt.Sprintf("%q", text)
+	case protocol.ActionKey:
+		return act.Key
+	case protocol.ActionScroll:
+		return fmt.Sprintf("%s %d", act.Direction, act.Amount)
+	case protocol.ActionFileRead, protocol.ActionFileWrite:
+		return act.Path
+	case protocol.ActionZoom:
+		return fmt.Sprintf("%dx%d at (%d, %d)", act.Width, act.Height, act.X, act.Y)
+	case protocol.ActionWait:
+		if act.Display != 0 {
+			return fmt.Sprintf("%dms, display %d", act.Ms, act.Display)
+		}
+		return fmt.Sprintf("%dms", act.Ms)
+	default:
+		return ""
+	}
+}
+
+// DescribeVerdict returns a short, human readable summary of a verdict,
+// such as the issues found, for display next to its type.
+func DescribeVerdict(verdict *protocol.Verdict) string {
+	switch verdict.Type {
+	case protocol.VerdictReject:
+		return strings.Join(verdict.Issues, "; ")
+	case protocol.VerdictRemediate:
+		text := fmt.Sprintf("%d fixes", len(verdict.Actions))
+		if len(verdict.Issues) > 0 {
+			text += " for " + strings.Join(verdict.Issues, "; ")
+		}
+		return text
+	default:
+		return verdict.Summary
+	}
+}
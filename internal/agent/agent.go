@@ -3,40 +3,157 @@ package agent
 import (
 	"context"
 	"fmt"
+	"image"
 	"sync"
 	"time"
 
 	"github.com/thesimpledev/golemming/internal/action"
 	"github.com/thesimpledev/golemming/internal/capture"
 	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
 	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/internal/session"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
+// cancelledAction answers the model's tool call for an action that was
+// never executed because its step was interrupted.
+const cancelledAction = "cancelled before execution: the step was interrupted, so this action did not run"
+
+// screenMovedError answers an action that was not executed because the
+// screen changed under it, most likely through another agent's input.
+const screenMovedError = "not executed: the screen changed before this action could run; look at the new screenshot"
+
+// A click is checked for changes in the square of targetRadius screenshot
+// pixels around its target, other input on the whole screen. The screen
+// counts as changed when movedFraction of the checked pixels differ, so a
+// blinking caret or a ticking clock does not block every action.
+const (
+	targetRadius  = 40
+	movedFraction = 0.05
+)
+
 // Agent represents an autonomous desktop automation agent.
 type Agent struct {
 	config   *config.Config
 	client   *llm.Client
+	screen   *capture.Capturer
 	executor *action.Executor
 	history  *History
 
-	goal    string
-	state   State
-	result  string
-	mu      sync.RWMutex
+	goal   string
+	state  State
+	result string
+	mu     sync.RWMutex
+
+	// Run control: stop cancels the whole run, interrupt the current step
+	// when pausing. resume wakes a paused run; stepOnce pauses it again
+	// after one step. stale is set by a pause, after which the operator
+	// may have used the desktop, so the next step captures afresh.
+	stop      context.CancelFunc
+	interrupt context.CancelFunc
+	resume    chan struct{}
+	stepOnce  bool
+	stale     bool
+
+	// notes holds operator notes waiting for the next step's prompt.
+	notes []Note
+
+	// next is the frame captured once the screen settled after the last
+	// action; nextUnchanged is set when that action changed nothing.
+	next          *capture.Frame
+	nextUnchanged bool
+
+	// recorder writes the run to disk when session recording is on; calls
+	// collects the model calls of the current step for it and fixes the
+	// verifier's remediation steps, written after the step they follow.
+	recorder *session.Recorder
+	calls    []session.Call
+	fixes    []*session.Step
+
+	// steps counts the step numbers handed out by nextStep.
+	steps int
+
+	// lease, when set, is held while input actions run so that agents
+	// sharing the desktop take turns; name identifies this agent to it.
+	lease *InputLease
+	name  string
+
+	// verifier, when set, reviews the work as configured by its mode.
+	verifier *Verifier
+
+	// approval selects the actions onApproval is asked about.
+	approval   approvalPolicy
+	onApproval func(ctx context.Context, req ApprovalRequest) (Decision, error)
 
-	onAction func(action *protocol.Action, result *action.Result)
+	onAction     func(action *protocol.Action, result *action.Result)
+	onScreenshot func(info ScreenshotInfo)
+	onVerdict    func(verdict *protocol.Verdict)
 }
 
-// New creates a new agent.
-func New(cfg *config.Config) *Agent {
-	return &Agent{
+// ScreenshotInfo describes the screenshot sent to the model for a step.
+type ScreenshotInfo struct {
+	Width, Height int
+	MediaType     string
+	Size          int // encoded bytes
+}
+
+// New creates a new agent that sends mouse and keyboard input through backend.
+func New(cfg *config.Config, backend input.Backend) (*Agent, error) {
+	return NewWithSource(cfg, backend, capture.NewSource())
+}
+
+// NewWithSource creates an agent like New that takes its screenshots from
+// src instead of the screen, e.g. synthetic images in tests.
+func NewWithSource(cfg *config.Config, backend input.Backend, src capture.Source) (*Agent, error) {
+	client, err := llm.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	screen := capture.NewCapturerFrom(src, cfg.Display, cfg.MaxScreenshotEdge)
+	if cfg.GridOverlay {
+		screen.SetGrid(cfg.GridCellSize)
+	}
+	approval, err := newApprovalPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Agent{
 		config:   cfg,
-		client:   llm.NewClient(cfg.APIKey, cfg.Model),
-		executor: action.NewExecutor(cfg.RequireAbsolutePaths),
+		approval: approval,
+		client:   client,
+		screen:   screen,
+		executor: action.NewExecutor(backend, screen, cfg.RequireAbsolutePaths),
 		history:  NewHistory(),
 		state:    StateIdle,
 	}
+	client.OnInvalidResponse(a.history.AddRejection)
+	client.OnExchange(a.recordCall(""))
+
+	if cfg.Verify != config.VerifyOff {
+		verifierClient, err := llm.NewClient(cfg.VerifierConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create verifier: %w", err)
+		}
+		verifierClient.OnInvalidResponse(a.history.AddVerifierRejection)
+		verifierClient.OnExchange(a.recordCall(session.SourceVerifier))
+		a.verifier = NewVerifier(verifierClient, cfg.Verify)
+	}
+	return a, nil
+}
+
+// recordCall returns an exchange callback that records the model call in
+// the session, marked with source.
+func (a *Agent) recordCall(source string) func(llm.Exchange) {
+	return func(ex llm.Exchange) {
+		if a.recorder != nil {
+			call := session.NewCall(ex)
+			call.Source = source
+			a.calls = append(a.calls, call)
+		}
+	}
 }
 
 // OnAction sets a callback for when an action is executed.
@@ -44,45 +161,118 @@ func (a *Agent) OnAction(fn func(action *protocol.Action, result *action.Result)
 	a.onAction = fn
 }
 
+// OnScreenshot sets a callback for each screenshot sent to the model.
+func (a *Agent) OnScreenshot(fn func(info ScreenshotInfo)) {
+	a.onScreenshot = fn
+}
+
+// OnRetry sets a callback for when a failed LLM call, of the agent or its
+// verifier, is about to be retried.
+func (a *Agent) OnRetry(fn func(event llm.RetryEvent)) {
+	a.client.OnRetry(fn)
+	if a.verifier != nil {
+		a.verifier.client.OnRetry(fn)
+	}
+}
+
+// SetInputLease makes the agent hold lease, as name, while it sends mouse
+// or keyboard input and waits for the screen to settle. Set it before Run.
+func (a *Agent) SetInputLease(lease *InputLease, name string) {
+	a.lease = lease
+	a.name = name
+}
+
+// SessionDir returns the directory the run is recorded to, or "" when
+// session recording is off.
+func (a *Agent) SessionDir() string {
+	if a.recorder == nil {
+		return ""
+	}
+	return a.recorder.Dir()
+}
+
 // Run starts the agent with the given goal.
-func (a *Agent) Run(ctx context.Context, goal string) error {
+func (a *Agent) Run(ctx context.Context, goal string) (err error) {
 	a.mu.Lock()
 	if a.state != StateIdle {
 		a.mu.Unlock()
 		return fmt.Errorf("agent is not idle (current state: %s)", a.state)
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	a.goal = goal
 	a.state = StateRunning
+	a.stop = cancel
+	a.resume = make(chan struct{}, 1)
 	a.mu.Unlock()
 
+	if a.config.RecordSessions {
+		if err := a.startRecording(); err != nil {
+			a.mu.Lock()
+			a.state = StateFailed
+			a.result = err.Error()
+			a.mu.Unlock()
+			return err
+		}
+		defer func() {
+			// Recording is best effort once the run is under way
+			_ = a.recorder.Finish(a.State().String(), a.Result(), err)
+		}()
+	}
+
 	defer func() {
 		a.mu.Lock()
-		if a.state == StateRunning {
+		if a.state == StateRunning || a.state == StatePaused {
 			a.state = StateStopped
 		}
 		a.mu.Unlock()
 	}()
 
-	for i := 0; i < a.config.MaxIterations; i++ {
-		select {
-		case <-ctx.Done():
+	for i := 0; i < a.config.MaxIterations; {
+		if err := a.waitWhilePaused(ctx); err != nil {
 			a.mu.Lock()
 			a.state = StateStopped
 			a.mu.Unlock()
-			return ctx.Err()
-		default:
+			return err
+		}
+
+		stepCtx, interrupt := context.WithCancel(ctx)
+		a.mu.Lock()
+		a.interrupt = interrupt
+		if a.stale {
+			// The frame kept from the last action may predate the pause
+			a.next, a.nextUnchanged = nil, false
+			a.stale = false
 		}
+		a.mu.Unlock()
+
+		done, err := a.step(stepCtx)
+		interrupted := stepCtx.Err() != nil
+		interrupt()
+
+		a.mu.Lock()
+		a.interrupt = nil
+		if a.stepOnce {
+			a.stepOnce = false
+			if a.state == StateRunning {
+				a.state = StatePaused
+				a.stale = true
+			}
+		}
+		a.mu.Unlock()
 
-		done, err := a.step(ctx)
 		if err != nil {
+			// A step cut short by Pause is taken again on Resume, even if
+			// Resume came before the step had unwound
+			if interrupted && ctx.Err() == nil {
+				continue
+			}
 			return err
 		}
 		if done {
 			return nil
 		}
-
-		// Wait for UI stabilization
-		time.Sleep(a.config.StabilizationDelay())
+		i++
 	}
 
 	a.mu.Lock()
@@ -92,22 +282,130 @@ func (a *Agent) Run(ctx context.Context, goal string) error {
 	return fmt.Errorf("max iterations (%d) reached", a.config.MaxIterations)
 }
 
+// startRecording creates the session directory for this run.
+func (a *Agent) startRecording() error {
+	dir := a.config.SessionDir
+	if dir == "" {
+		var err error
+		if dir, err = session.DefaultDir(); err != nil {
+			return fmt.Errorf("failed to start session recording: %w", err)
+		}
+	}
+
+	info := session.Info{
+		Goal:     a.goal,
+		Provider: a.config.Provider,
+		Model:    a.config.Model,
+		Display:  a.config.Display,
+		MaxEdge:  a.config.MaxScreenshotEdge,
+	}
+	if a.config.GridOverlay {
+		info.GridCellSize = a.config.GridCellSize
+	}
+
+	recorder, err := session.NewRecorder(dir, info, llm.SystemPrompt)
+	if err != nil {
+		return fmt.Errorf("failed to start session recording: %w", err)
+	}
+	a.recorder = recorder
+	return nil
+}
+
+// recordStep writes a finished step to the session, if recording.
+func (a *Agent) recordStep(rec *session.Step, start time.Time, err error) {
+	if a.recorder == nil {
+		return
+	}
+	rec.Calls, a.calls = a.calls, nil
+	rec.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	_ = a.recorder.WriteStep(rec)
+	for _, fix := range a.fixes {
+		_ = a.recorder.WriteStep(fix)
+	}
+	a.fixes = nil
+}
+
+// nextStep numbers a step, or a verifier fix, for the session. Every
+// attempt gets its own number, so a step taken again after a pause does
+// not overwrite the interrupted attempt's record and screenshot.
+func (a *Agent) nextStep() int {
+	a.steps++
+	return a.steps
+}
+
 // step executes a single agent step. Returns true if the agent is done.
-func (a *Agent) step(ctx context.Context) (bool, error) {
-	// Capture screenshot
-	screenshot, err := capture.CaptureAndEncode()
+func (a *Agent) step(ctx context.Context) (done bool, err error) {
+	start := time.Now()
+	rec := &session.Step{Step: a.nextStep(), Time: start}
+	defer func() { a.recordStep(rec, start, err) }()
+
+	// Use the frame captured after the last action, if any
+	frame, unchanged := a.next, a.nextUnchanged
+	a.next, a.nextUnchanged = nil, false
+	if frame == nil {
+		var err error
+		if frame, err = a.screen.Capture(); err != nil {
+			return false, fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+	}
+	encoded, err := capture.Encode(frame.Annotated(), a.config.ScreenshotFormat, a.config.ScreenshotQuality)
 	if err != nil {
-		return false, fmt.Errorf("failed to capture screenshot: %w", err)
+		return false, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	if a.onScreenshot != nil {
+		a.onScreenshot(ScreenshotInfo{
+			Width:     frame.Image.Bounds().Dx(),
+			Height:    frame.Image.Bounds().Dy(),
+			MediaType: encoded.MediaType,
+			Size:      encoded.Size,
+		})
+	}
+	screenshot := llm.Screenshot{
+		Data:        encoded.Data,
+		MediaType:   encoded.MediaType,
+		Description: frame.Describe(),
+		Unchanged:   unchanged,
+	}
+	if a.recorder != nil {
+		rec.Screen = screenshot.Description
+		rec.Screenshot, _ = a.recorder.SaveScreenshot(rec.Step, encoded.MediaType, encoded.Data)
 	}
 
-	// Get action from LLM
-	nextAction, err := a.client.GetAction(ctx, a.goal, screenshot, a.history.GetLLMHistory())
+	// Get action from LLM, passing on any operator notes
+	notes := a.pendingNotes()
+	texts := make([]string, len(notes))
+	for i, note := range notes {
+		texts[i] = note.Text
+	}
+	nextAction, err := a.client.GetAction(ctx, a.goal, screenshot, a.history.GetLLMHistory(), texts)
 	if err != nil {
 		return false, fmt.Errorf("failed to get action from LLM: %w", err)
 	}
+	a.deliverNotes(notes, rec.Step)
+	rec.Action = nextAction
+
+	// A step cut short before its action runs, e.g. by a pause during the
+	// approval prompt, must not have the model believe the action ran
+	executed := false
+	defer func() {
+		if err != nil && !executed {
+			a.client.CancelAction(cancelledAction)
+		}
+	}()
 
 	// Check for terminal actions
 	if nextAction.Type == protocol.ActionDone {
+		if a.verifier != nil && a.verifier.mode == config.VerifyPostTask {
+			verdict, approved, err := a.review(ctx, frame, true)
+			rec.Verdict = verdict
+			if err != nil || !approved {
+				return false, err
+			}
+		}
+
 		a.mu.Lock()
 		a.state = StateCompleted
 		a.result = nextAction.Summary
@@ -123,21 +421,191 @@ func (a *Agent) step(ctx context.Context) (bool, error) {
 		return true, nil
 	}
 
+	// Ask the operator first if the approval policy says so
+	if a.approval.needs(nextAction) {
+		approved, rejection, err := a.approve(ctx, nextAction, frame)
+		if err != nil {
+			return false, err
+		}
+		if rejection != nil {
+			a.history.Add(action.ToHistoryEntry(nextAction, rejection))
+			rec.Result = rejection
+			if a.onAction != nil {
+				a.onAction(nextAction, rejection)
+			}
+			return false, nil
+		}
+		nextAction = approved
+		rec.Action = nextAction
+	}
+
+	// Take turns with other agents on the same desktop
+	leased, moved, err := a.takeInput(ctx, nextAction, frame)
+	if err != nil {
+		return false, err
+	}
+	if moved != nil {
+		a.history.Add(action.ToHistoryEntry(nextAction, moved))
+		rec.Result = moved
+		if a.onAction != nil {
+			a.onAction(nextAction, moved)
+		}
+		return false, nil
+	}
+
 	// Execute the action
+	executed = true
 	result := a.executor.Execute(nextAction)
 
-	// Record in history
+	// Wait for the UI to settle and check whether the action did anything
 	historyEntry := action.ToHistoryEntry(nextAction, result)
+	if result.Success {
+		a.settle(ctx)
+		if after, err := a.screen.Capture(); err == nil {
+			a.next = after
+			a.observe(nextAction, &historyEntry, frame, after)
+			if a.nextUnchanged {
+				a.awaitLateChange(ctx, nextAction, &historyEntry, frame)
+			}
+		}
+	}
+	if leased {
+		a.lease.Release()
+	}
+
+	// Record in history
 	a.history.Add(historyEntry)
+	rec.Result = result
+	rec.Observation = historyEntry.Observation
 
 	// Notify callback
 	if a.onAction != nil {
 		a.onAction(nextAction, result)
 	}
 
+	// Have the verifier check the step; an action that changed nothing
+	// leaves nothing new to review
+	if result.Success && !a.nextUnchanged && a.wantsReview(nextAction) {
+		current := a.next
+		if current == nil {
+			if current, err = a.screen.Capture(); err != nil {
+				return false, fmt.Errorf("failed to capture screenshot: %w", err)
+			}
+		}
+		verdict, _, err := a.review(ctx, current, false)
+		rec.Verdict = verdict
+		if err != nil {
+			return false, err
+		}
+		if verdict.Type == protocol.VerdictRemediate {
+			a.next, a.nextUnchanged = nil, false
+		}
+	}
+
 	return false, nil
 }
 
+// settle waits until the screen stops changing, or the stabilization
+// timeout passes.
+func (a *Agent) settle(ctx context.Context) {
+	_, _ = a.screen.WaitStable(ctx, a.config.StabilizationDelay(), a.config.StabilizationTimeout())
+}
+
+// takeInput acquires the input lease for act, when the agent shares the
+// desktop, and checks under it that the screen still shows what the model
+// saw in frame. Another agent's input may have changed it while the model
+// decided; then the lease is released again and act is answered with a
+// failed result instead of running, and the next step sees the new screen.
+func (a *Agent) takeInput(ctx context.Context, act *protocol.Action, frame *capture.Frame) (leased bool, moved *action.Result, err error) {
+	if a.lease == nil || !usesInput(act.Type) {
+		return false, nil, nil
+	}
+	if err := a.lease.Acquire(ctx, a.name); err != nil {
+		return false, nil, err
+	}
+
+	current, err := a.screen.Capture()
+	if err != nil {
+		return true, nil, nil
+	}
+	if !screenMoved(act, frame, current) {
+		return true, nil, nil
+	}
+	a.lease.Release()
+	a.next, a.nextUnchanged = current, false
+	return false, &action.Result{Error: screenMovedError}, nil
+}
+
+// screenMoved reports whether current differs from frame enough that act,
+// chosen by looking at frame, may no longer do what was meant: around the
+// target of a click, or anywhere for other input.
+func screenMoved(act *protocol.Action, frame, current *capture.Frame) bool {
+	region := frame.Image.Bounds()
+	if act.Type == protocol.ActionClick {
+		x, y := act.X, act.Y
+		if act.Cell != "" && frame.Grid != nil {
+			var err error
+			if x, y, err = frame.Grid.Cell(act.Cell); err != nil {
+				return false
+			}
+		}
+		region = image.Rect(x-targetRadius, y-targetRadius, x+targetRadius, y+targetRadius)
+	}
+	change, ok := capture.CompareRegion(frame, current, region)
+	return !ok || change.Fraction >= movedFraction
+}
+
+// observe compares the frames before and after an action and notes in the
+// history entry what changed on screen.
+func (a *Agent) observe(act *protocol.Action, entry *llm.HistoryEntry, before, after *capture.Frame) {
+	change, ok := capture.Compare(before, after)
+	if !ok {
+		return
+	}
+	if !change.Visible() {
+		if usesInput(act.Type) {
+			entry.Observation = change.String()
+			a.nextUnchanged = true
+		}
+		return
+	}
+
+	entry.Observation = change.String()
+	if a.config.HighlightChanges {
+		after.Highlight = change.Bounds
+	}
+}
+
+// awaitLateChange keeps watching the screen, for up to the stabilization
+// timeout, after an action that showed no visible change, so that the model
+// is not asked about a screen the action has not reached yet, e.g. a dialog
+// that takes a second to open. A late change replaces the observation.
+func (a *Agent) awaitLateChange(ctx context.Context, act *protocol.Action, entry *llm.HistoryEntry, before *capture.Frame) {
+	changed, err := a.screen.WaitChange(ctx, before, a.config.StabilizationTimeout())
+	if err != nil || changed == nil {
+		return
+	}
+	a.settle(ctx)
+	after, err := a.screen.Capture()
+	if err != nil {
+		return
+	}
+	a.next, a.nextUnchanged = after, false
+	a.observe(act, entry, before, after)
+}
+
+// usesInput reports whether an action sends mouse or keyboard input. Such
+// actions are expected to change what is on screen, so that no change is
+// worth pointing out to the model, and run under the input lease.
+func usesInput(t protocol.ActionType) bool {
+	switch t {
+	case protocol.ActionClick, protocol.ActionType_, protocol.ActionKey, protocol.ActionScroll:
+		return true
+	default:
+		return false
+	}
+}
+
 // State returns the current agent state.
 func (a *Agent) State() State {
 	a.mu.RLock()
@@ -157,11 +625,117 @@ func (a *Agent) History() *History {
 	return a.history
 }
 
-// Stop stops the agent.
+// Stop stops the agent, cancelling any model call or wait in progress.
 func (a *Agent) Stop() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	if a.state == StateRunning {
+	if a.state == StateRunning || a.state == StatePaused {
 		a.state = StateStopped
 	}
+	if a.stop != nil {
+		a.stop()
+	}
+}
+
+// AddNote sends guidance from the operator to the agent. It is passed to
+// the model with the next step's prompt and recorded in the history.
+func (a *Agent) AddNote(text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.notes = append(a.notes, Note{Timestamp: time.Now(), Text: text})
+}
+
+// pendingNotes returns the notes not yet passed to the model.
+func (a *Agent) pendingNotes() []Note {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]Note(nil), a.notes...)
+}
+
+// deliverNotes records notes the model received at step and drops them
+// from the pending ones. Notes of a step that failed before the model
+// answered stay pending for the next attempt.
+func (a *Agent) deliverNotes(notes []Note, step int) {
+	if len(notes) == 0 {
+		return
+	}
+	a.mu.Lock()
+	a.notes = a.notes[len(notes):]
+	a.mu.Unlock()
+	for _, note := range notes {
+		note.Step = step
+		a.history.AddNote(note)
+	}
+}
+
+// Pause stops the agent before its next action. A step in progress is
+// interrupted, cancelling its model call, unless its action is already
+// executing, and is taken again on Resume.
+func (a *Agent) Pause() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state != StateRunning {
+		return
+	}
+	a.state = StatePaused
+	a.stepOnce = false
+	a.stale = true
+	// Drop a wake left over from an earlier resume
+	select {
+	case <-a.resume:
+	default:
+	}
+	if a.interrupt != nil {
+		a.interrupt()
+	}
+}
+
+// Resume continues a paused agent.
+func (a *Agent) Resume() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state != StatePaused {
+		return
+	}
+	a.state = StateRunning
+	a.wake()
+}
+
+// Step runs exactly one step of a paused agent and pauses it again.
+func (a *Agent) Step() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.state != StatePaused {
+		return
+	}
+	a.state = StateRunning
+	a.stepOnce = true
+	a.wake()
+}
+
+// wake signals a paused run to check its state. It never blocks, as
+// callers hold a.mu; a pending wake is enough for the run to see the new
+// state.
+func (a *Agent) wake() {
+	select {
+	case a.resume <- struct{}{}:
+	default:
+	}
+}
+
+// waitWhilePaused blocks while the agent is paused.
+func (a *Agent) waitWhilePaused(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if a.State() != StatePaused {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.resume:
+		}
+	}
 }
@@ -0,0 +1,380 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/internal/session"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// fakeSource is a capture.Source with one 1280x720 display showing a
+// fixed synthetic image.
+type fakeSource struct {
+	mu       sync.Mutex
+	img      *image.RGBA
+	captures int
+	changes  int
+}
+
+func newFakeSource() *fakeSource {
+	img := image.NewRGBA(image.Rect(0, 0, 1280, 720))
+	for y := 0; y < 720; y++ {
+		for x := 0; x < 1280; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	return &fakeSource{img: img}
+}
+
+func (s *fakeSource) Displays() []image.Rectangle {
+	return []image.Rectangle{s.img.Bounds()}
+}
+
+func (s *fakeSource) CaptureRect(bounds image.Rectangle) (*image.RGBA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.captures++
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		copy(out.Pix[y*out.Stride:(y+1)*out.Stride], s.img.Pix[s.img.PixOffset(bounds.Min.X, bounds.Min.Y+y):])
+	}
+	return out, nil
+}
+
+// change repaints a corner of the display, as an application reacting to
+// input would.
+func (s *fakeSource) change() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes++
+	shade := uint8(s.changes * 64)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			s.img.Set(x, y, color.RGBA{shade, 255 - shade, 0, 255})
+		}
+	}
+}
+
+// reactiveBackend records input and changes the screen after every key
+// press, click or typed text, after delay.
+type reactiveBackend struct {
+	*input.Recorder
+	src   *fakeSource
+	delay time.Duration
+}
+
+func (b *reactiveBackend) react() {
+	if b.delay == 0 {
+		b.src.change()
+		return
+	}
+	time.AfterFunc(b.delay, b.src.change)
+}
+
+func (b *reactiveBackend) Click(button string, double bool) error {
+	b.react()
+	return b.Recorder.Click(button, double)
+}
+
+func (b *reactiveBackend) KeyPress(key string) error {
+	b.react()
+	return b.Recorder.KeyPress(key)
+}
+
+func (b *reactiveBackend) KeyCombo(combo string) error {
+	b.react()
+	return b.Recorder.KeyCombo(combo)
+}
+
+func (b *reactiveBackend) TypeText(text string) error {
+	b.react()
+	return b.Recorder.TypeText(text)
+}
+
+// scriptConfig returns a configuration whose model replays actions in
+// order, with short waits for the screen to settle.
+func scriptConfig(t *testing.T, actions ...protocol.Action) *config.Config {
+	t.Helper()
+	steps := make([]llm.ScriptStep, len(actions))
+	for i, act := range actions {
+		steps[i] = llm.ScriptStep{Action: act}
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = config.ProviderScript
+	cfg.ScriptPath = path
+	cfg.MaxRetries = 0
+	cfg.StabilizationMs = 10
+	cfg.StabilizationTimeoutMs = 100
+	return cfg
+}
+
+func TestRunScripted(t *testing.T) {
+	cfg := scriptConfig(t,
+		protocol.Action{Type: protocol.ActionClick, X: 100, Y: 50},
+		protocol.Action{Type: protocol.ActionType_, Text: "hello"},
+		protocol.Action{Type: protocol.ActionDone, Summary: "typed hello"},
+	)
+	cfg.MaxScreenshotEdge = 640 // clicks are mapped back to the 1280 wide display
+
+	rec := input.NewRecorder()
+	ag, err := NewWithSource(cfg, rec, newFakeSource())
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+
+	if err := ag.Run(context.Background(), "type hello"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if ag.State() != StateCompleted || ag.Result() != "typed hello" {
+		t.Errorf("finished %s with %q, want completed with %q", ag.State(), ag.Result(), "typed hello")
+	}
+
+	want := []input.Event{
+		{Kind: "move", X: 201, Y: 101},
+		{Kind: "click", Button: "left"},
+		{Kind: "type", Text: "hello"},
+	}
+	if got := rec.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("input = %+v, want %+v", got, want)
+	}
+
+	history := ag.History()
+	if history.Len() != 2 {
+		t.Fatalf("history has %d entries, want 2", history.Len())
+	}
+	// The synthetic screen never reacts to input
+	if got := history.All()[0].LLMEntry.Observation; got != "no visible change" {
+		t.Errorf("click observation = %q, want %q", got, "no visible change")
+	}
+}
+
+func TestRunScriptExhausted(t *testing.T) {
+	cfg := scriptConfig(t, protocol.Action{Type: protocol.ActionKey, Key: "enter"})
+
+	ag, err := NewWithSource(cfg, input.NewRecorder(), newFakeSource())
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	if err := ag.Run(context.Background(), "press enter"); err == nil {
+		t.Fatal("Run succeeded after the script ran out")
+	}
+	if ag.State() != StateStopped {
+		t.Errorf("state = %s, want stopped", ag.State())
+	}
+}
+
+// transcriptProvider answers with each action in turn and keeps every
+// request it receives. deciding, if set, is called with the request number
+// while the model decides.
+type transcriptProvider struct {
+	actions  []protocol.Action
+	requests []*llm.Request
+	deciding func(n int)
+}
+
+func (p *transcriptProvider) Complete(ctx context.Context, req *llm.Request) (llm.Message, error) {
+	p.requests = append(p.requests, req)
+	if p.deciding != nil {
+		p.deciding(len(p.requests))
+	}
+	if len(p.requests) > len(p.actions) {
+		return llm.Message{}, fmt.Errorf("no action for request %d", len(p.requests))
+	}
+	data, err := json.Marshal(p.actions[len(p.requests)-1])
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{
+		Role: llm.RoleAssistant,
+		Content: []llm.Block{{
+			Type:      llm.BlockToolUse,
+			ToolID:    fmt.Sprintf("call-%d", len(p.requests)),
+			ToolName:  string(p.actions[len(p.requests)-1].Type),
+			ToolInput: data,
+		}},
+	}, nil
+}
+
+func TestPauseDuringApproval(t *testing.T) {
+	cfg := scriptConfig(t, protocol.Action{Type: protocol.ActionDone})
+	cfg.ConversationMode = true
+	cfg.Approval = config.ApprovalAlways
+	cfg.RecordSessions = true
+	cfg.SessionDir = t.TempDir()
+
+	rec := input.NewRecorder()
+	ag, err := NewWithSource(cfg, rec, newFakeSource())
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	provider := &transcriptProvider{actions: []protocol.Action{
+		{Type: protocol.ActionKey, Key: "enter"},
+		{Type: protocol.ActionKey, Key: "tab"},
+		{Type: protocol.ActionDone, Summary: "pressed tab"},
+	}}
+	ag.client = llm.NewClientWithProvider(provider, cfg)
+
+	// The first prompt is interrupted by a pause and the run resumed
+	// before the step has unwound
+	approvals := 0
+	ag.OnApproval(func(ctx context.Context, req ApprovalRequest) (Decision, error) {
+		approvals++
+		if approvals == 1 {
+			ag.Pause()
+			<-ctx.Done()
+			ag.Resume()
+			return Decision{}, ctx.Err()
+		}
+		return Decision{Approved: true}, nil
+	})
+
+	if err := ag.Run(context.Background(), "press tab"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if ag.State() != StateCompleted {
+		t.Fatalf("state = %s, want completed", ag.State())
+	}
+	want := []input.Event{{Kind: "key_press", Key: "tab"}}
+	if got := rec.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("input = %+v, want %+v", got, want)
+	}
+
+	// The model is told the interrupted call never ran, and the call that
+	// did run is answered with its result
+	results := make(map[string]llm.Block)
+	for _, msg := range provider.requests[len(provider.requests)-1].Messages {
+		for _, block := range msg.Content {
+			if block.Type == llm.BlockToolResult {
+				results[block.ToolID] = block
+			}
+		}
+	}
+	if first := results["call-1"]; !first.IsError || !strings.Contains(first.Text, "cancelled before execution") {
+		t.Errorf("interrupted call answered with %+v, want a cancellation error", first)
+	}
+	if second := results["call-2"]; second.IsError {
+		t.Errorf("executed call answered with an error: %+v", second)
+	}
+
+	// The interrupted attempt keeps its own record and screenshot
+	_, steps, err := session.Load(ag.SessionDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 3 {
+		t.Fatalf("recorded %d steps, want 3", len(steps))
+	}
+	for i, step := range steps {
+		if step.Step != i+1 || step.Screenshot == "" {
+			t.Errorf("step %d recorded as %d with screenshot %q", i+1, step.Step, step.Screenshot)
+		}
+	}
+	if first := steps[0]; first.Error == "" || first.Result != nil {
+		t.Errorf("interrupted attempt recorded as %+v, want an error and no result", first)
+	}
+	if screenshots := []string{steps[0].Screenshot, steps[1].Screenshot}; screenshots[0] == screenshots[1] {
+		t.Errorf("interrupted attempt and retry share screenshot %s", screenshots[0])
+	}
+}
+
+func TestLateChangeObserved(t *testing.T) {
+	cfg := scriptConfig(t,
+		protocol.Action{Type: protocol.ActionClick, X: 100, Y: 50},
+		protocol.Action{Type: protocol.ActionDone, Summary: "opened"},
+	)
+	cfg.StabilizationTimeoutMs = 2000
+
+	// The screen reacts only after the quiet period has passed
+	src := newFakeSource()
+	backend := &reactiveBackend{Recorder: input.NewRecorder(), src: src, delay: 400 * time.Millisecond}
+	ag, err := NewWithSource(cfg, backend, src)
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	if err := ag.Run(context.Background(), "open the dialog"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	got := ag.History().All()[0].LLMEntry.Observation
+	if !strings.HasPrefix(got, "region 0,0-") {
+		t.Errorf("click observation = %q, want the late change", got)
+	}
+}
+
+func TestScreenChangedBeforeInput(t *testing.T) {
+	cfg := scriptConfig(t, protocol.Action{Type: protocol.ActionDone})
+	src := newFakeSource()
+	rec := input.NewRecorder()
+	ag, err := NewWithSource(cfg, rec, src)
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	ag.SetInputLease(NewInputLease(), "agent")
+
+	// Another agent repaints the corner while the model decides on the
+	// first two actions
+	provider := &transcriptProvider{
+		actions: []protocol.Action{
+			{Type: protocol.ActionClick, X: 20, Y: 20},
+			{Type: protocol.ActionKey, Key: "enter"},
+			{Type: protocol.ActionClick, X: 20, Y: 20},
+			{Type: protocol.ActionDone, Summary: "clicked"},
+		},
+		deciding: func(n int) {
+			if n <= 2 {
+				src.change()
+			}
+		},
+	}
+	ag.client = llm.NewClientWithProvider(provider, cfg)
+
+	if err := ag.Run(context.Background(), "click the corner"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The click on the repainted corner is held back; the key press, for
+	// which the small change does not matter, and the retried click run
+	want := []input.Event{
+		{Kind: "key_press", Key: "enter"},
+		{Kind: "move", X: 20, Y: 20},
+		{Kind: "click", Button: "left"},
+	}
+	if got := rec.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("input = %+v, want %+v", got, want)
+	}
+	entries := ag.History().All()
+	if len(entries) != 3 {
+		t.Fatalf("history has %d entries, want 3", len(entries))
+	}
+	if got := entries[0].LLMEntry.Error; !strings.Contains(got, "screen changed") {
+		t.Errorf("held back click answered with %q, want the screen change", got)
+	}
+	for _, entry := range entries[1:] {
+		if entry.LLMEntry.Error != "" {
+			t.Errorf("%s failed: %s", entry.LLMEntry.Action.Type, entry.LLMEntry.Error)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"regexp"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// ApprovalRequest is an action the model proposed that waits for the
+// operator's decision before it is executed.
+type ApprovalRequest struct {
+	Action *protocol.Action
+
+	// For clicks, where the click would land: Target in screenshot pixels
+	// and Screen in global screen coordinates.
+	HasTarget bool
+	Target    image.Point
+	Screen    image.Point
+}
+
+// Decision is the operator's answer to an ApprovalRequest.
+type Decision struct {
+	Approved bool
+	Feedback string           // why the action was rejected, passed on to the model
+	Action   *protocol.Action // replacement for the proposed action, if edited
+}
+
+// OnApproval sets the function asked to approve actions the approval policy
+// selects. It blocks until the operator decides; ctx is cancelled if the
+// run stops first.
+func (a *Agent) OnApproval(fn func(ctx context.Context, req ApprovalRequest) (Decision, error)) {
+	a.onApproval = fn
+}
+
+// EditAction returns a copy of act with the fields given in the JSON object
+// data replacing its own, e.g. {"x": 420} to move a click. The result is
+// validated.
+func EditAction(act *protocol.Action, data string) (*protocol.Action, error) {
+	edited := *act
+	if err := json.Unmarshal([]byte(data), &edited); err != nil {
+		return nil, fmt.Errorf("invalid action JSON: %w", err)
+	}
+	if err := edited.Validate(); err != nil {
+		return nil, err
+	}
+	return &edited, nil
+}
+
+// approvalPolicy decides which actions need the operator's approval.
+type approvalPolicy struct {
+	mode string
+	keys *regexp.Regexp // for config.ApprovalKeys
+}
+
+// newApprovalPolicy builds the policy configured in cfg.
+func newApprovalPolicy(cfg *config.Config) (approvalPolicy, error) {
+	policy := approvalPolicy{mode: cfg.Approval}
+	if cfg.Approval == config.ApprovalKeys {
+		if cfg.ApprovalKeyPattern == "" {
+			return policy, fmt.Errorf("approval policy %q requires an approval key pattern", config.ApprovalKeys)
+		}
+		keys, err := regexp.Compile(cfg.ApprovalKeyPattern)
+		if err != nil {
+			return policy, fmt.Errorf("invalid approval key pattern: %w", err)
+		}
+		policy.keys = keys
+	}
+	return policy, nil
+}
+
+// needs reports whether act must be approved before it is executed.
+func (p approvalPolicy) needs(act *protocol.Action) bool {
+	switch p.mode {
+	case config.ApprovalAlways:
+		return true
+	case config.ApprovalFileWrite:
+		return act.Type == protocol.ActionFileWrite
+	case config.ApprovalKeys:
+		return act.Type == protocol.ActionKey && p.keys.MatchString(act.Key)
+	default:
+		return false
+	}
+}
+
+// approve asks the operator about act, proposed for the screen in frame.
+// It returns the action to execute, possibly edited, or nil and the
+// rejection, ready to record as the action's result.
+func (a *Agent) approve(ctx context.Context, act *protocol.Action, frame *capture.Frame) (*protocol.Action, *action.Result, error) {
+	if a.onApproval == nil {
+		return nil, nil, fmt.Errorf("%s action needs approval but nothing is set up to ask for it", act.Type)
+	}
+
+	req := ApprovalRequest{Action: act}
+	if act.Type == protocol.ActionClick {
+		x, y := act.X, act.Y
+		if act.Cell != "" && frame.Grid != nil {
+			if cx, cy, err := frame.Grid.Cell(act.Cell); err == nil {
+				x, y = cx, cy
+			}
+		}
+		sx, sy := frame.ToScreen(x, y)
+		req.HasTarget = true
+		req.Target = image.Pt(x, y)
+		req.Screen = image.Pt(sx, sy)
+	}
+
+	decision, err := a.onApproval(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get approval: %w", err)
+	}
+	if !decision.Approved {
+		reason := "rejected by operator"
+		if decision.Feedback != "" {
+			reason += ": " + decision.Feedback
+		}
+		return nil, &action.Result{Success: false, Error: reason}, nil
+	}
+
+	if decision.Action != nil {
+		if decision.Action.Type == protocol.ActionDone || decision.Action.Type == protocol.ActionFailed {
+			return nil, &action.Result{Success: false, Error: "edited action cannot be " + string(decision.Action.Type)}, nil
+		}
+		if err := decision.Action.Validate(); err != nil {
+			return nil, &action.Result{Success: false, Error: "edited action is invalid: " + err.Error()}, nil
+		}
+		return decision.Action, nil, nil
+	}
+	return act, nil, nil
+}
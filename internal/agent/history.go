@@ -4,11 +4,15 @@ import (
 	"time"
 
 	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/internal/session"
+	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
 // History tracks the action history for an agent.
 type History struct {
-	entries []Entry
+	entries    []Entry
+	rejections []Rejection
+	notes      []Note
 }
 
 // Entry represents a single history entry with timestamp.
@@ -17,6 +21,24 @@ type Entry struct {
 	LLMEntry  llm.HistoryEntry
 }
 
+// Rejection records a model response that was rejected as unparseable or
+// invalid and sent back for correction.
+type Rejection struct {
+	Timestamp time.Time
+	Step      int    // 1-based step the response was for
+	Attempt   int    // 1 for the first response of the step
+	Response  string // raw model response
+	Error     string
+	Source    string // session.SourceVerifier for the verifier's responses, else ""
+}
+
+// Note is guidance the operator sent to the agent during a run.
+type Note struct {
+	Timestamp time.Time // when the operator sent it
+	Step      int       // 1-based step whose prompt carried it
+	Text      string
+}
+
 // NewHistory creates a new history tracker.
 func NewHistory() *History {
 	return &History{
@@ -58,3 +80,55 @@ func (h *History) Last() *Entry {
 func (h *History) All() []Entry {
 	return h.entries
 }
+
+// AddRejection records a rejected model response for the next step.
+func (h *History) AddRejection(err *llm.InvalidResponseError) {
+	h.addRejection(err, "")
+}
+
+// AddVerifierRejection records a rejected response of the verifier.
+func (h *History) AddVerifierRejection(err *llm.InvalidResponseError) {
+	h.addRejection(err, session.SourceVerifier)
+}
+
+func (h *History) addRejection(err *llm.InvalidResponseError, source string) {
+	h.rejections = append(h.rejections, Rejection{
+		Timestamp: time.Now(),
+		Step:      len(h.entries) + 1,
+		Attempt:   err.Attempt,
+		Response:  err.Response,
+		Error:     err.Err.Error(),
+		Source:    source,
+	})
+}
+
+// Rejections returns all rejected model responses.
+func (h *History) Rejections() []Rejection {
+	return h.rejections
+}
+
+// AddNote records an operator note delivered to the model.
+func (h *History) AddNote(note Note) {
+	h.notes = append(h.notes, note)
+}
+
+// Notes returns all operator notes delivered to the model.
+func (h *History) Notes() []Note {
+	return h.notes
+}
+
+// TouchedFiles returns the paths of files written successfully, in the
+// order they were first written.
+func (h *History) TouchedFiles() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, entry := range h.entries {
+		record := entry.LLMEntry
+		if record.Action.Type != string(protocol.ActionFileWrite) || record.Error != "" || seen[record.Action.Path] {
+			continue
+		}
+		seen[record.Action.Path] = true
+		files = append(files, record.Action.Path)
+	}
+	return files
+}
@@ -10,6 +10,7 @@ const (
 	StateCompleted
 	StateFailed
 	StateStopped
+	StatePaused
 )
 
 func (s State) String() string {
@@ -24,6 +25,8 @@ func (s State) String() string {
 		return "failed"
 	case StateStopped:
 		return "stopped"
+	case StatePaused:
+		return "paused"
 	default:
 		return "unknown"
 	}
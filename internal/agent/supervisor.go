@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
+)
+
+// InputLease serializes use of the mouse and keyboard, which every agent on
+// the desktop shares. An agent holds it while an input action runs and the
+// screen settles, so actions of different agents never interleave.
+//
+// The lease is not held while the model decides, which would make agents
+// take turns for whole steps, so another agent's input can change the
+// screen between an agent's screenshot and its action. Once it holds the
+// lease, an agent captures the screen again and does not run an action
+// whose target changed, showing the model the new screen instead. Agents
+// that work in the same window should still claim it as a resource, as
+// changes away from a click's target go unnoticed.
+type InputLease struct {
+	sem    chan struct{}
+	mu     sync.Mutex
+	holder string
+}
+
+// NewInputLease creates a free input lease.
+func NewInputLease() *InputLease {
+	return &InputLease{sem: make(chan struct{}, 1)}
+}
+
+// Acquire blocks until the lease is free or ctx is done.
+func (l *InputLease) Acquire(ctx context.Context, holder string) error {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	l.holder = holder
+	l.mu.Unlock()
+	return nil
+}
+
+// Release frees the lease.
+func (l *InputLease) Release() {
+	l.mu.Lock()
+	l.holder = ""
+	l.mu.Unlock()
+	<-l.sem
+}
+
+// Holder returns the name of the agent holding the lease, or "" if free.
+func (l *InputLease) Holder() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holder
+}
+
+// Spec describes an agent to launch under a Supervisor.
+type Spec struct {
+	Name   string // defaults to "agent-<id>"
+	Goal   string
+	Config *config.Config
+
+	// Resources are names of things the agent needs to itself for its whole
+	// run, such as "window:Notepad". Two agents holding the same resource
+	// never run at the same time.
+	Resources []string
+}
+
+// ErrTooManyAgents is returned by Launch when the agent limit is reached.
+var ErrTooManyAgents = errors.New("too many agents running")
+
+// ConflictError is returned by Launch when a resource the new agent needs
+// is held by a running agent.
+type ConflictError struct {
+	Resource string
+	Holder   string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("resource %q is in use by %s", e.Resource, e.Holder)
+}
+
+// Managed is an agent run by a Supervisor.
+type Managed struct {
+	ID        int
+	Name      string
+	Goal      string
+	Resources []string
+	Agent     *Agent
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Done is closed when the agent's run has ended.
+func (m *Managed) Done() <-chan struct{} {
+	return m.done
+}
+
+// Wait blocks until the agent's run has ended and returns its error.
+func (m *Managed) Wait() error {
+	<-m.done
+	return m.err
+}
+
+// Err returns the error the run ended with, or nil while it is running.
+func (m *Managed) Err() error {
+	select {
+	case <-m.done:
+		return m.err
+	default:
+		return nil
+	}
+}
+
+// running reports whether the agent's run has not ended yet.
+func (m *Managed) running() bool {
+	select {
+	case <-m.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Supervisor launches agents in parallel, tracks them and cancels them. All
+// agents send input through the same backend, serialized by an InputLease.
+type Supervisor struct {
+	backend   input.Backend
+	source    capture.Source // nil for the screen
+	lease     *InputLease
+	maxAgents int
+
+	mu     sync.Mutex
+	agents []*Managed
+	nextID int
+	wg     sync.WaitGroup
+
+	onFinish func(m *Managed)
+}
+
+// NewSupervisor creates a supervisor whose agents send mouse and keyboard
+// input through backend. maxAgents limits how many run at once; 0 means no
+// limit.
+func NewSupervisor(backend input.Backend, maxAgents int) *Supervisor {
+	return &Supervisor{
+		backend:   backend,
+		lease:     NewInputLease(),
+		maxAgents: maxAgents,
+	}
+}
+
+// OnFinish sets a callback for when an agent's run ends. It is called from
+// the agent's goroutine.
+func (s *Supervisor) OnFinish(fn func(m *Managed)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onFinish = fn
+}
+
+// SetSource makes the supervisor's agents take their screenshots from src
+// instead of the screen. Set it before Launch.
+func (s *Supervisor) SetSource(src capture.Source) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source = src
+}
+
+// Lease returns the input lease shared by the supervisor's agents.
+func (s *Supervisor) Lease() *InputLease {
+	return s.lease
+}
+
+// Launch creates an agent for spec and starts it in its own goroutine. The
+// run is cancelled when ctx is done or through Cancel.
+func (s *Supervisor) Launch(ctx context.Context, spec Spec) (*Managed, error) {
+	if spec.Config == nil {
+		return nil, fmt.Errorf("agent spec has no config")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := 0
+	for _, m := range s.agents {
+		if !m.running() {
+			continue
+		}
+		running++
+		for _, want := range spec.Resources {
+			for _, held := range m.Resources {
+				if want == held {
+					return nil, &ConflictError{Resource: want, Holder: m.Name}
+				}
+			}
+		}
+	}
+	if s.maxAgents > 0 && running >= s.maxAgents {
+		return nil, fmt.Errorf("%w (limit %d)", ErrTooManyAgents, s.maxAgents)
+	}
+
+	s.nextID++
+	id := s.nextID
+	name := spec.Name
+	if name == "" {
+		name = fmt.Sprintf("agent-%d", id)
+	}
+
+	src := s.source
+	if src == nil {
+		src = capture.NewSource()
+	}
+	ag, err := NewWithSource(spec.Config, s.backend, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	ag.SetInputLease(s.lease, name)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	m := &Managed{
+		ID:        id,
+		Name:      name,
+		Goal:      spec.Goal,
+		Resources: append([]string(nil), spec.Resources...),
+		Agent:     ag,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	s.agents = append(s.agents, m)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		m.err = ag.Run(runCtx, spec.Goal)
+		close(m.done)
+
+		s.mu.Lock()
+		onFinish := s.onFinish
+		s.mu.Unlock()
+		if onFinish != nil {
+			onFinish(m)
+		}
+	}()
+
+	return m, nil
+}
+
+// Get returns the agent with the given ID, or nil.
+func (s *Supervisor) Get(id int) *Managed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.agents {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// Agents returns every agent launched so far, in launch order.
+func (s *Supervisor) Agents() []*Managed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Managed(nil), s.agents...)
+}
+
+// Cancel stops the agent with the given ID.
+func (s *Supervisor) Cancel(id int) error {
+	m := s.Get(id)
+	if m == nil {
+		return fmt.Errorf("no agent with ID %d", id)
+	}
+	m.cancel()
+	return nil
+}
+
+// CancelAll stops every running agent.
+func (s *Supervisor) CancelAll() {
+	for _, m := range s.Agents() {
+		m.cancel()
+	}
+}
+
+// Wait blocks until every launched agent has finished.
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
+
+// Status counts the supervisor's agents by state.
+type Status struct {
+	Total     int
+	Idle      int
+	Running   int
+	Paused    int
+	Completed int
+	Failed    int
+	Stopped   int
+}
+
+// State summarizes the agents as one state: running while any agent is
+// still going, paused if those left are all paused, then failed if any
+// failed, stopped if any was stopped, and completed only if all completed.
+func (st Status) State() State {
+	switch {
+	case st.Running > 0 || st.Idle > 0:
+		if st.Total == st.Idle {
+			return StateIdle
+		}
+		return StateRunning
+	case st.Paused > 0:
+		return StatePaused
+	case st.Failed > 0:
+		return StateFailed
+	case st.Stopped > 0:
+		return StateStopped
+	case st.Completed > 0:
+		return StateCompleted
+	default:
+		return StateIdle
+	}
+}
+
+// Status returns the aggregate state of all launched agents.
+func (s *Supervisor) Status() Status {
+	var st Status
+	for _, m := range s.Agents() {
+		st.Total++
+		switch m.Agent.State() {
+		case StateIdle:
+			st.Idle++
+		case StateRunning:
+			st.Running++
+		case StatePaused:
+			st.Paused++
+		case StateCompleted:
+			st.Completed++
+		case StateFailed:
+			st.Failed++
+		case StateStopped:
+			st.Stopped++
+		}
+	}
+	return st
+}
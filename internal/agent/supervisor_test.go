@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// gatedBackend records input along with the lease holder at the time, and
+// blocks the first move until release is closed.
+type gatedBackend struct {
+	*input.Recorder
+	lease   *InputLease
+	started chan struct{} // closed when the first move arrives
+	release chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	holders []string
+}
+
+func (b *gatedBackend) Move(x, y int) error {
+	b.once.Do(func() {
+		close(b.started)
+		<-b.release
+	})
+	b.mu.Lock()
+	b.holders = append(b.holders, b.lease.Holder())
+	b.mu.Unlock()
+	return b.Recorder.Move(x, y)
+}
+
+func (b *gatedBackend) Click(button string, double bool) error {
+	b.mu.Lock()
+	b.holders = append(b.holders, b.lease.Holder())
+	b.mu.Unlock()
+	return b.Recorder.Click(button, double)
+}
+
+func TestSupervisorLeaseAndConflicts(t *testing.T) {
+	backend := &gatedBackend{
+		Recorder: input.NewRecorder(),
+		started:  make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	s := NewSupervisor(backend, 0)
+	backend.lease = s.Lease()
+	s.SetSource(newFakeSource())
+
+	clickAndDone := func() []protocol.Action {
+		return []protocol.Action{
+			{Type: protocol.ActionClick, X: 10, Y: 10},
+			{Type: protocol.ActionDone, Summary: "clicked"},
+		}
+	}
+
+	ctx := context.Background()
+	first, err := s.Launch(ctx, Spec{Name: "first", Goal: "click", Config: scriptConfig(t, clickAndDone()...), Resources: []string{"window:Notepad"}})
+	if err != nil {
+		t.Fatalf("Launch first: %v", err)
+	}
+	<-backend.started // first now holds the lease, blocked in its click
+
+	_, err = s.Launch(ctx, Spec{Name: "clash", Goal: "click", Config: scriptConfig(t, clickAndDone()...), Resources: []string{"window:Notepad"}})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Holder != "first" {
+		t.Fatalf("Launch with a held resource: got %v, want a conflict with first", err)
+	}
+
+	second, err := s.Launch(ctx, Spec{Name: "second", Goal: "click", Config: scriptConfig(t, clickAndDone()...), Resources: []string{"window:Calculator"}})
+	if err != nil {
+		t.Fatalf("Launch second: %v", err)
+	}
+	if holder := s.Lease().Holder(); holder != "first" {
+		t.Errorf("lease held by %q while first clicks, want first", holder)
+	}
+
+	close(backend.release)
+	s.Wait()
+
+	for _, m := range []*Managed{first, second} {
+		if err := m.Err(); err != nil || m.Agent.State() != StateCompleted {
+			t.Errorf("%s finished %s: %v", m.Name, m.Agent.State(), err)
+		}
+	}
+	if st := s.Status(); st.Total != 2 || st.Completed != 2 || st.State() != StateCompleted {
+		t.Errorf("status = %+v, want 2 completed", st)
+	}
+
+	// Each click's move and press happened under one agent's lease
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if len(backend.holders) != 4 {
+		t.Fatalf("got %d input calls, want 4", len(backend.holders))
+	}
+	for i := 0; i < len(backend.holders); i += 2 {
+		move, click := backend.holders[i], backend.holders[i+1]
+		if move == "" || move != click {
+			t.Errorf("click %d sent under leases %q and %q", i/2+1, move, click)
+		}
+	}
+	if backend.holders[0] != "first" || backend.holders[2] != "second" {
+		t.Errorf("lease holders = %v, want first then second", backend.holders)
+	}
+}
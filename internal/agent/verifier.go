@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/internal/session"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// reviewAction is the history entry type recording a verifier's feedback.
+const reviewAction = "review"
+
+// Verifier is a checker agent that reviews the work of a primary agent and
+// approves it, rejects it with issues or proposes remediation actions.
+type Verifier struct {
+	client *llm.Client
+	mode   string
+}
+
+// NewVerifier creates a verifier that asks client for verdicts. mode is
+// config.VerifyPostTask or config.VerifyPerStep.
+func NewVerifier(client *llm.Client, mode string) *Verifier {
+	return &Verifier{client: client, mode: mode}
+}
+
+// Mode returns when the verifier reviews the agent's work.
+func (v *Verifier) Mode() string {
+	return v.mode
+}
+
+// Verify reviews the work recorded in history against goal, given the
+// current screen and the files the agent wrote. final is set when the agent
+// has reported the goal done.
+func (v *Verifier) Verify(ctx context.Context, goal string, history *History, shot llm.Screenshot, files []string, final bool) (*protocol.Verdict, error) {
+	verdict, err := v.client.GetVerdict(ctx, goal, shot, history.GetLLMHistory(), files, final)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verdict: %w", err)
+	}
+	return verdict, nil
+}
+
+// SetVerifier has v review the agent's work; nil turns review off. Set it
+// before Run.
+func (a *Agent) SetVerifier(v *Verifier) {
+	a.verifier = v
+}
+
+// OnVerdict sets a callback for each verdict given by the verifier.
+func (a *Agent) OnVerdict(fn func(verdict *protocol.Verdict)) {
+	a.onVerdict = fn
+}
+
+// review asks the verifier about the work so far, with frame as the current
+// screen, and acts on the verdict. It returns the verdict and whether the
+// work was approved; otherwise the feedback, and any remediation, is in the
+// history for the agent's next step.
+func (a *Agent) review(ctx context.Context, frame *capture.Frame, final bool) (*protocol.Verdict, bool, error) {
+	encoded, err := capture.Encode(frame.Annotated(), a.config.ScreenshotFormat, a.config.ScreenshotQuality)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	shot := llm.Screenshot{
+		Data:        encoded.Data,
+		MediaType:   encoded.MediaType,
+		Description: frame.Describe(),
+	}
+
+	verdict, err := a.verifier.Verify(ctx, a.goal, a.history, shot, a.history.TouchedFiles(), final)
+	if err != nil {
+		return nil, false, err
+	}
+	if a.onVerdict != nil {
+		a.onVerdict(verdict)
+	}
+
+	switch verdict.Type {
+	case protocol.VerdictReject:
+		a.history.Add(llm.HistoryEntry{
+			Action: llm.ActionRecord{Type: reviewAction},
+			Error:  "rejected by verifier: " + strings.Join(verdict.Issues, "; "),
+		})
+		return verdict, false, nil
+
+	case protocol.VerdictRemediate:
+		entry := llm.HistoryEntry{
+			Action:      llm.ActionRecord{Type: reviewAction},
+			Observation: fmt.Sprintf("the verifier applied %d fixes", len(verdict.Actions)),
+		}
+		if len(verdict.Issues) > 0 {
+			entry.Observation += " for: " + strings.Join(verdict.Issues, "; ")
+		}
+		a.history.Add(entry)
+		if err := a.remediate(ctx, verdict.Actions, frame); err != nil {
+			return verdict, false, err
+		}
+		return verdict, false, nil
+
+	default:
+		return verdict, true, nil
+	}
+}
+
+// remediate executes the verifier's fixes, proposed for the screen in
+// frame, and records them in the history and session. The fixes go through
+// the same approval policy as the agent's own actions; the first one that
+// is rejected or fails ends the remediation.
+func (a *Agent) remediate(ctx context.Context, actions []protocol.Action, frame *capture.Frame) error {
+	for i := range actions {
+		act := &actions[i]
+		start := time.Now()
+
+		var result *action.Result
+		if a.approval.needs(act) {
+			approved, rejection, err := a.approve(ctx, act, frame)
+			if err != nil {
+				return err
+			}
+			if rejection != nil {
+				result = rejection
+			} else {
+				act = approved
+			}
+		}
+
+		if result == nil {
+			leased, moved, err := a.takeInput(ctx, act, frame)
+			if err != nil {
+				return err
+			}
+			if moved != nil {
+				result = moved
+			} else {
+				result = a.executor.Execute(act)
+				if result.Success {
+					a.settle(ctx)
+					if current, err := a.screen.Capture(); err == nil {
+						frame = current
+					}
+				}
+			}
+			if leased {
+				a.lease.Release()
+			}
+		}
+
+		a.history.Add(action.ToHistoryEntry(act, result))
+		if a.recorder != nil {
+			a.fixes = append(a.fixes, &session.Step{
+				Step:       a.nextStep(),
+				Time:       start,
+				Source:     session.SourceVerifier,
+				Action:     act,
+				Result:     result,
+				DurationMs: time.Since(start).Milliseconds(),
+			})
+		}
+		if a.onAction != nil {
+			a.onAction(act, result)
+		}
+		if !result.Success {
+			break
+		}
+	}
+	return nil
+}
+
+// wantsReview reports whether the verifier should look at the step that
+// just executed act.
+func (a *Agent) wantsReview(act *protocol.Action) bool {
+	if a.verifier == nil || a.verifier.mode != config.VerifyPerStep {
+		return false
+	}
+	return usesInput(act.Type) || act.Type == protocol.ActionFileWrite
+}
@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/internal/session"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// verdictProvider is a verifier model giving each verdict in turn and
+// approving once they run out.
+type verdictProvider struct {
+	verdicts []protocol.Verdict
+	calls    int
+}
+
+func (p *verdictProvider) Complete(ctx context.Context, req *llm.Request) (llm.Message, error) {
+	verdict := protocol.Verdict{Type: protocol.VerdictApprove}
+	if p.calls < len(p.verdicts) {
+		verdict = p.verdicts[p.calls]
+	}
+	p.calls++
+
+	data, err := json.Marshal(verdict)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{
+		Role: llm.RoleAssistant,
+		Content: []llm.Block{{
+			Type:      llm.BlockToolUse,
+			ToolID:    fmt.Sprintf("verdict-%d", p.calls),
+			ToolName:  string(verdict.Type),
+			ToolInput: data,
+		}},
+	}, nil
+}
+
+func TestRemediationApprovedAndRecorded(t *testing.T) {
+	cfg := scriptConfig(t,
+		protocol.Action{Type: protocol.ActionKey, Key: "enter"},
+		protocol.Action{Type: protocol.ActionDone, Summary: "saved"},
+	)
+	cfg.Approval = config.ApprovalFileWrite
+	cfg.RecordSessions = true
+	cfg.SessionDir = t.TempDir()
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	provider := &verdictProvider{verdicts: []protocol.Verdict{{
+		Type:   protocol.VerdictRemediate,
+		Issues: []string{"not saved"},
+		Actions: []protocol.Action{
+			{Type: protocol.ActionKey, Key: "ctrl+s"},
+			{Type: protocol.ActionFileWrite, Path: path, Content: "saved"},
+			{Type: protocol.ActionKey, Key: "escape"},
+		},
+	}}}
+
+	src := newFakeSource()
+	rec := &reactiveBackend{Recorder: input.NewRecorder(), src: src}
+	ag, err := NewWithSource(cfg, rec, src)
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	ag.SetVerifier(NewVerifier(llm.NewClientWithProvider(provider, cfg), config.VerifyPerStep))
+
+	var asked []protocol.ActionType
+	ag.OnApproval(func(ctx context.Context, req ApprovalRequest) (Decision, error) {
+		asked = append(asked, req.Action.Type)
+		return Decision{Approved: false, Feedback: "no writes"}, nil
+	})
+
+	if err := ag.Run(context.Background(), "save the file"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The rejected write ends the remediation before escape is pressed
+	if !reflect.DeepEqual(asked, []protocol.ActionType{protocol.ActionFileWrite}) {
+		t.Errorf("approval asked for %v, want only the file write", asked)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("rejected file write ran: %v", err)
+	}
+	var keys []string
+	for _, event := range rec.Events() {
+		keys = append(keys, event.Key)
+	}
+	if want := []string{"enter", "ctrl+s"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys pressed = %v, want %v", keys, want)
+	}
+
+	// The fixes are recorded after the step they follow, so a replay runs
+	// them in the same order
+	_, steps, err := session.Load(ag.SessionDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	type recorded struct {
+		source  string
+		action  protocol.ActionType
+		success bool
+	}
+	var got []recorded
+	for _, step := range steps {
+		r := recorded{source: step.Source, action: step.Action.Type}
+		if step.Result != nil {
+			r.success = step.Result.Success
+		}
+		got = append(got, r)
+	}
+	want := []recorded{
+		{"", protocol.ActionKey, true},
+		{session.SourceVerifier, protocol.ActionKey, true},
+		{session.SourceVerifier, protocol.ActionFileWrite, false},
+		{"", protocol.ActionDone, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded steps = %+v, want %+v", got, want)
+	}
+
+	seen := make(map[int]bool)
+	for _, step := range steps {
+		if seen[step.Step] {
+			t.Errorf("step number %d recorded twice", step.Step)
+		}
+		seen[step.Step] = true
+	}
+}
+
+func TestUnchangedStepNotReviewed(t *testing.T) {
+	cfg := scriptConfig(t,
+		protocol.Action{Type: protocol.ActionKey, Key: "enter"},
+		protocol.Action{Type: protocol.ActionDone, Summary: "pressed"},
+	)
+
+	// The synthetic screen never reacts, so there is nothing to review
+	provider := &verdictProvider{}
+	ag, err := NewWithSource(cfg, input.NewRecorder(), newFakeSource())
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	ag.SetVerifier(NewVerifier(llm.NewClientWithProvider(provider, cfg), config.VerifyPerStep))
+
+	if err := ag.Run(context.Background(), "press enter"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if provider.calls != 0 {
+		t.Errorf("verifier asked %d times about a step that changed nothing", provider.calls)
+	}
+}
+
+func TestScriptedVerifier(t *testing.T) {
+	cfg := scriptConfig(t,
+		protocol.Action{Type: protocol.ActionKey, Key: "enter"},
+		protocol.Action{Type: protocol.ActionDone, Summary: "saved"},
+		protocol.Action{Type: protocol.ActionDone, Summary: "saved, really"},
+	)
+	cfg.RecordSessions = true
+	cfg.SessionDir = t.TempDir()
+
+	// The verifier has its own script: one invalid response, sent back for
+	// correction, a rejection and an approval
+	data, err := json.Marshal([]llm.ScriptStep{
+		{Verdict: &protocol.Verdict{Type: "maybe"}},
+		{Verdict: &protocol.Verdict{Type: protocol.VerdictReject, Issues: []string{"not saved"}}},
+		{Verdict: &protocol.Verdict{Type: protocol.VerdictApprove, Summary: "saved"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Verify = config.VerifyPostTask
+	cfg.VerifyProvider = config.ProviderScript
+	cfg.VerifyScriptPath = filepath.Join(t.TempDir(), "verifier.json")
+	if err := os.WriteFile(cfg.VerifyScriptPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ag, err := NewWithSource(cfg, input.NewRecorder(), newFakeSource())
+	if err != nil {
+		t.Fatalf("NewWithSource: %v", err)
+	}
+	if err := ag.Run(context.Background(), "save the file"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if ag.State() != StateCompleted || ag.Result() != "saved, really" {
+		t.Fatalf("finished %s with %q, want completed after the rejection", ag.State(), ag.Result())
+	}
+
+	rejections := ag.History().Rejections()
+	if len(rejections) != 1 || rejections[0].Source != session.SourceVerifier {
+		t.Errorf("rejections = %+v, want the verifier's invalid response", rejections)
+	}
+
+	_, steps, err := session.Load(ag.SessionDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verdicts []protocol.VerdictType
+	agentCalls, verifierCalls := 0, 0
+	for _, step := range steps {
+		if step.Verdict != nil {
+			verdicts = append(verdicts, step.Verdict.Type)
+		}
+		for _, call := range step.Calls {
+			if call.Source == session.SourceVerifier {
+				verifierCalls++
+			} else {
+				agentCalls++
+			}
+		}
+	}
+	if want := []protocol.VerdictType{protocol.VerdictReject, protocol.VerdictApprove}; !reflect.DeepEqual(verdicts, want) {
+		t.Errorf("recorded verdicts %v, want %v", verdicts, want)
+	}
+	if agentCalls != 3 || verifierCalls != 3 {
+		t.Errorf("recorded %d agent and %d verifier calls, want 3 of each", agentCalls, verifierCalls)
+	}
+}
@@ -0,0 +1,218 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+)
+
+const (
+	// pixelTolerance is the summed RGB difference below which two pixels
+	// count as equal, absorbing JPEG-like noise and subpixel rendering.
+	pixelTolerance = 24
+
+	// minVisiblePixels is how many pixels must differ for an action to
+	// count as having changed the screen. A ticked checkbox, one typed
+	// character or a moved caret change a few dozen pixels; isolated
+	// pixels are rendering noise.
+	minVisiblePixels = 8
+
+	// settleFraction is the share of pixels of a probe frame that may
+	// still change while the screen counts as settled, so that a blinking
+	// caret or spinner does not hold up every step until the timeout.
+	settleFraction = 0.0005
+
+	// probeEdge is the size of the cheap frames compared while waiting for
+	// the screen to settle.
+	probeEdge = 320
+
+	// pollInterval is how often the screen is probed while waiting.
+	pollInterval = 100 * time.Millisecond
+)
+
+// Diff returns the fraction of pixels that differ between a and b, from 0
+// for identical images to 1. Images of different sizes differ entirely.
+func Diff(a, b *image.RGBA) float64 {
+	_, fraction := ChangedBounds(a, b)
+	return fraction
+}
+
+// ChangedBounds returns the smallest rectangle, in image coordinates,
+// containing every pixel that differs between a and b, and the fraction of
+// pixels that differ. Images of different sizes differ entirely.
+func ChangedBounds(a, b *image.RGBA) (image.Rectangle, float64) {
+	bounds, changed := changedPixels(a, b)
+	area := b.Bounds().Dx() * b.Bounds().Dy()
+	if changed == 0 || area == 0 {
+		return bounds, 0
+	}
+	return bounds, float64(changed) / float64(area)
+}
+
+// changedPixels returns the bounds of the pixels that differ between a
+// and b, and how many differ. Images of different sizes differ entirely.
+func changedPixels(a, b *image.RGBA) (image.Rectangle, int) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Dx() != bb.Dx() || ab.Dy() != bb.Dy() {
+		return image.Rect(0, 0, bb.Dx(), bb.Dy()), bb.Dx() * bb.Dy()
+	}
+	if ab.Empty() {
+		return image.Rectangle{}, 0
+	}
+
+	changed := 0
+	minX, minY, maxX, maxY := ab.Dx(), ab.Dy(), -1, -1
+	for y := 0; y < ab.Dy(); y++ {
+		rowA := a.Pix[y*a.Stride : y*a.Stride+ab.Dx()*4]
+		rowB := b.Pix[y*b.Stride : y*b.Stride+bb.Dx()*4]
+		for i := 0; i < len(rowA); i += 4 {
+			if pixelDelta(rowA[i:i+3], rowB[i:i+3]) > pixelTolerance {
+				changed++
+				x := i / 4
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+		}
+	}
+	if changed == 0 {
+		return image.Rectangle{}, 0
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1), changed
+}
+
+// pixelDelta sums the absolute differences of the RGB channels.
+func pixelDelta(a, b []uint8) int {
+	d := 0
+	for i := range a {
+		if a[i] > b[i] {
+			d += int(a[i] - b[i])
+		} else {
+			d += int(b[i] - a[i])
+		}
+	}
+	return d
+}
+
+// Change describes what changed between two frames of the same view.
+type Change struct {
+	Bounds   image.Rectangle // changed area in the later frame's image coordinates
+	Pixels   int             // number of pixels that changed
+	Fraction float64         // share of pixels that changed
+}
+
+// Compare returns the change from before to after, or false if the frames
+// show different views (another display or zoom level) and so cannot be
+// compared.
+func Compare(before, after *Frame) (Change, bool) {
+	return CompareRegion(before, after, after.Image.Bounds())
+}
+
+// CompareRegion is like Compare but looks only at region, in image
+// coordinates. The fraction is of the region's pixels.
+func CompareRegion(before, after *Frame, region image.Rectangle) (Change, bool) {
+	if before.Bounds != after.Bounds || before.Zoomed != after.Zoomed {
+		return Change{}, false
+	}
+	a := before.Image.SubImage(region).(*image.RGBA)
+	b := after.Image.SubImage(region).(*image.RGBA)
+	bounds, changed := changedPixels(a, b)
+	change := Change{Pixels: changed}
+	if changed > 0 {
+		change.Bounds = bounds.Add(b.Bounds().Min.Sub(after.Image.Bounds().Min))
+	}
+	if area := b.Bounds().Dx() * b.Bounds().Dy(); area > 0 {
+		change.Fraction = float64(changed) / float64(area)
+	}
+	return change, true
+}
+
+// Visible reports whether the change is large enough to be noticed.
+func (c Change) Visible() bool {
+	return c.Pixels >= minVisiblePixels
+}
+
+// String summarizes the change for the model, e.g.
+// "region 420,310-900,600 changed; 3% of screen".
+func (c Change) String() string {
+	if !c.Visible() {
+		return "no visible change"
+	}
+	percent := fmt.Sprintf("%.0f%%", c.Fraction*100)
+	if c.Fraction < 0.01 {
+		percent = "<1%"
+	}
+	return fmt.Sprintf("region %d,%d-%d,%d changed; %s of screen",
+		c.Bounds.Min.X, c.Bounds.Min.Y, c.Bounds.Max.X, c.Bounds.Max.Y, percent)
+}
+
+// WaitStable polls the current view until it has stayed unchanged for
+// settle, or until timeout. It reports whether the screen settled; a
+// screen still animating at the timeout is not an error.
+func (c *Capturer) WaitStable(ctx context.Context, settle, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+
+	prev, err := c.probe()
+	if err != nil {
+		return false, err
+	}
+	stableSince := time.Now()
+
+	for {
+		if time.Since(stableSince) >= settle {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+
+		next, err := c.probe()
+		if err != nil {
+			return false, err
+		}
+		if Diff(prev, next) >= settleFraction {
+			stableSince = time.Now()
+		}
+		prev = next
+	}
+}
+
+// WaitChange polls the current view until it visibly differs from since,
+// or until timeout, and returns the first frame that does, or nil.
+func (c *Capturer) WaitChange(ctx context.Context, since *Frame, timeout time.Duration) (*Frame, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+
+		frame, err := c.Capture()
+		if err != nil {
+			return nil, err
+		}
+		if change, ok := Compare(since, frame); !ok || change.Visible() {
+			return frame, nil
+		}
+	}
+	return nil, nil
+}
+
+// probe grabs a small copy of the current view for change detection.
+func (c *Capturer) probe() (*image.RGBA, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frame, err := c.grab()
+	if err != nil {
+		return nil, err
+	}
+	return Downscale(frame.Image, probeEdge), nil
+}
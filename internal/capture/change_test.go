@@ -0,0 +1,161 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+// frameOf wraps an image as a frame of the primary display.
+func frameOf(img *image.RGBA) *Frame {
+	return &Frame{Image: img, Display: 1, Count: 1, Bounds: img.Bounds(), Scale: 1}
+}
+
+// paint returns a copy of img with r filled with c.
+func paint(img *image.RGBA, r image.Rectangle, c color.RGBA) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	copy(out.Pix, img.Pix)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			out.SetRGBA(x, y, c)
+		}
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	before := solid(1280, 720, color.RGBA{30, 30, 30, 255})
+
+	tests := []struct {
+		name    string
+		after   *image.RGBA
+		visible bool
+		bounds  image.Rectangle
+	}{
+		{"identical", before, false, image.Rectangle{}},
+		{"noise", paint(before, image.Rect(10, 10, 13, 11), white), false, image.Rect(10, 10, 13, 11)},
+		{"subtle shift", paint(before, image.Rect(0, 0, 200, 200), color.RGBA{35, 35, 35, 255}), false, image.Rectangle{}},
+		{"caret", paint(before, image.Rect(400, 300, 401, 316), white), true, image.Rect(400, 300, 401, 316)},
+		{"typed character", paint(before, image.Rect(400, 300, 406, 309), white), true, image.Rect(400, 300, 406, 309)},
+		{"checkbox tick", paint(before, image.Rect(600, 200, 610, 210), white), true, image.Rect(600, 200, 610, 210)},
+		{"dialog", paint(before, image.Rect(300, 200, 900, 500), white), true, image.Rect(300, 200, 900, 500)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, ok := Compare(frameOf(before), frameOf(tt.after))
+			if !ok {
+				t.Fatal("frames of the same view not compared")
+			}
+			if change.Visible() != tt.visible {
+				t.Errorf("Visible() = %v with %d pixels changed, want %v", change.Visible(), change.Pixels, tt.visible)
+			}
+			if change.Bounds != tt.bounds {
+				t.Errorf("bounds = %v, want %v", change.Bounds, tt.bounds)
+			}
+			if !tt.visible && change.String() != "no visible change" {
+				t.Errorf("String() = %q, want no visible change", change.String())
+			}
+		})
+	}
+}
+
+func TestCompareDifferentViews(t *testing.T) {
+	img := solid(640, 360, color.RGBA{A: 255})
+	before, after := frameOf(img), frameOf(img)
+	after.Bounds = image.Rect(1920, 0, 2560, 360)
+	if _, ok := Compare(before, after); ok {
+		t.Error("frames of different displays compared")
+	}
+
+	after = frameOf(img)
+	after.Zoomed = true
+	if _, ok := Compare(before, after); ok {
+		t.Error("zoomed and unzoomed frames compared")
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	c := Change{Bounds: image.Rect(420, 310, 900, 600), Pixels: 27600, Fraction: 0.03}
+	if got, want := c.String(), "region 420,310-900,600 changed; 3% of screen"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	c = Change{Bounds: image.Rect(400, 300, 406, 309), Pixels: 54, Fraction: 54.0 / (1280 * 720)}
+	if got, want := c.String(), "region 400,300-406,309 changed; <1% of screen"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// staticSource is a Source whose single display never changes.
+type staticSource struct{ img *image.RGBA }
+
+func (s staticSource) Displays() []image.Rectangle { return []image.Rectangle{s.img.Bounds()} }
+
+func (s staticSource) CaptureRect(bounds image.Rectangle) (*image.RGBA, error) {
+	return s.img.SubImage(bounds).(*image.RGBA), nil
+}
+
+func TestWaitStable(t *testing.T) {
+	c := NewCapturerFrom(staticSource{solid(1280, 720, color.RGBA{A: 255})}, 1, 0)
+	start := time.Now()
+	settled, err := c.WaitStable(context.Background(), 150*time.Millisecond, time.Second)
+	if err != nil || !settled {
+		t.Fatalf("WaitStable = %v, %v; want settled", settled, err)
+	}
+	if waited := time.Since(start); waited < 150*time.Millisecond {
+		t.Errorf("settled after %v, before the quiet period", waited)
+	}
+}
+
+func TestWaitChange(t *testing.T) {
+	img := solid(1280, 720, color.RGBA{A: 255})
+	c := NewCapturerFrom(staticSource{img}, 1, 0)
+	before, err := c.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The source hands out its own pixels, so keep a copy
+	before.Image = solid(1280, 720, color.RGBA{A: 255})
+
+	// Nothing changes: wait for the whole timeout
+	after, err := c.WaitChange(context.Background(), before, 250*time.Millisecond)
+	if err != nil || after != nil {
+		t.Fatalf("WaitChange on a static screen = %v, %v; want nil", after, err)
+	}
+
+	// A change made before waiting began still counts
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	after, err = c.WaitChange(context.Background(), before, time.Second)
+	if err != nil || after == nil {
+		t.Fatalf("WaitChange = %v, %v; want the changed frame", after, err)
+	}
+	if change, _ := Compare(before, after); change.Pixels != 100 {
+		t.Errorf("changed frame differs by %d pixels, want 100", change.Pixels)
+	}
+}
+
+func TestCompareRegion(t *testing.T) {
+	before := solid(1280, 720, color.RGBA{30, 30, 30, 255})
+	after := paint(before, image.Rect(300, 200, 340, 240), color.RGBA{255, 255, 255, 255})
+
+	change, ok := CompareRegion(frameOf(before), frameOf(after), image.Rect(280, 180, 360, 260))
+	if !ok {
+		t.Fatal("frames of the same view not compared")
+	}
+	if change.Pixels != 1600 || change.Bounds != image.Rect(300, 200, 340, 240) {
+		t.Errorf("change = %d pixels in %v, want 1600 in 300,200-340,240", change.Pixels, change.Bounds)
+	}
+	if change.Fraction != 0.25 {
+		t.Errorf("fraction = %v, want 0.25 of the region", change.Fraction)
+	}
+
+	if change, _ := CompareRegion(frameOf(before), frameOf(after), image.Rect(0, 0, 100, 100)); change.Pixels != 0 {
+		t.Errorf("change outside the region counted: %d pixels", change.Pixels)
+	}
+}
@@ -0,0 +1,293 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Zoom limits. Regions smaller than minZoomSize are rejected, and zoomed
+// captures are never magnified more than maxZoomFactor.
+const (
+	minZoomSize   = 16
+	maxZoomFactor = 4.0
+)
+
+// AllDisplays selects the whole virtual desktop, with every display
+// stitched into one image at its position in global screen space.
+const AllDisplays = -1
+
+// Frame is a captured screenshot together with where it came from, so that
+// coordinates in the image can be mapped back to the screen.
+type Frame struct {
+	Image   *image.RGBA     // possibly resized from Bounds
+	Display int             // 1-based display number, or AllDisplays
+	Count   int             // number of displays when captured
+	Bounds  image.Rectangle // captured area in global screen coordinates
+	Scale   float64         // image pixels per screen pixel, 1 if not resized
+	Zoomed  bool            // Bounds is a region selected with Zoom
+	Grid    *Grid           // overlay drawn by Annotated, nil when off
+
+	// Highlight is an area outlined by Annotated, such as the region that
+	// changed since the previous frame. Empty for none.
+	Highlight image.Rectangle
+}
+
+// Annotated returns the image to show the model: a copy of Image with the
+// grid overlay and highlight drawn on it, or Image itself when there is
+// nothing to draw.
+func (f *Frame) Annotated() *image.RGBA {
+	if f.Grid == nil && f.Highlight.Empty() {
+		return f.Image
+	}
+	img := image.NewRGBA(f.Image.Bounds())
+	copy(img.Pix, f.Image.Pix)
+	if f.Grid != nil {
+		DrawGrid(img, *f.Grid)
+	}
+	if !f.Highlight.Empty() {
+		DrawOutline(img, f.Highlight)
+	}
+	return img
+}
+
+// ToScreen converts a point in the image to global screen coordinates. A
+// point maps to the centre of the screen area its image pixel covers.
+func (f *Frame) ToScreen(x, y int) (int, int) {
+	img := f.Image.Bounds()
+	return f.Bounds.Min.X + scaleCoord(x, img.Dx(), f.Bounds.Dx()),
+		f.Bounds.Min.Y + scaleCoord(y, img.Dy(), f.Bounds.Dy())
+}
+
+// FromScreen converts global screen coordinates to a point in the image.
+func (f *Frame) FromScreen(x, y int) (int, int) {
+	img := f.Image.Bounds()
+	return scaleCoord(x-f.Bounds.Min.X, f.Bounds.Dx(), img.Dx()),
+		scaleCoord(y-f.Bounds.Min.Y, f.Bounds.Dy(), img.Dy())
+}
+
+// scaleCoord maps pixel v on an axis of size from onto an axis of size to.
+func scaleCoord(v, from, to int) int {
+	if from == to || from == 0 {
+		return v
+	}
+	return int(math.Floor((float64(v) + 0.5) * float64(to) / float64(from)))
+}
+
+// Describe tells the model what the screenshot shows.
+func (f *Frame) Describe() string {
+	var view string
+	if f.Display == AllDisplays {
+		view = fmt.Sprintf("All %d displays stitched together", f.Count)
+	} else {
+		view = fmt.Sprintf("Display %d of %d", f.Display, f.Count)
+	}
+
+	var text string
+	img := f.Image.Bounds()
+	if f.Zoomed {
+		text = fmt.Sprintf("Zoomed view of %s: a %dx%d region magnified %.1fx to %dx%d. Use zoom_reset to see the whole display again.",
+			strings.ToLower(view[:1])+view[1:], f.Bounds.Dx(), f.Bounds.Dy(), f.Scale, img.Dx(), img.Dy())
+	} else {
+		size := fmt.Sprintf("%dx%d", f.Bounds.Dx(), f.Bounds.Dy())
+		if img.Dx() != f.Bounds.Dx() || img.Dy() != f.Bounds.Dy() {
+			size += fmt.Sprintf(", shown at %dx%d", img.Dx(), img.Dy())
+		}
+		text = fmt.Sprintf("%s (%s).", view, size)
+	}
+
+	if f.Grid != nil {
+		text += " " + f.Grid.Describe()
+	}
+	return text
+}
+
+// stitch captures the displays at rects from src into one image covering
+// their combined bounds.
+func stitch(src Source, rects []image.Rectangle) (*image.RGBA, image.Rectangle, error) {
+	if len(rects) == 0 {
+		return nil, image.Rectangle{}, fmt.Errorf("no active displays found")
+	}
+
+	var union image.Rectangle
+	for _, r := range rects {
+		union = union.Union(r)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, union.Dx(), union.Dy()))
+	for i, r := range rects {
+		img, err := src.CaptureRect(r)
+		if err != nil {
+			return nil, image.Rectangle{}, fmt.Errorf("failed to capture display %d: %w", i+1, err)
+		}
+		at := r.Min.Sub(union.Min)
+		draw.Draw(canvas, img.Bounds().Sub(img.Bounds().Min).Add(at), img, img.Bounds().Min, draw.Src)
+	}
+	return canvas, union, nil
+}
+
+// Capturer captures the display currently selected for the agent and
+// remembers the last frame for coordinate mapping.
+type Capturer struct {
+	mu      sync.Mutex
+	src     Source
+	display int
+	maxEdge int
+	region  image.Rectangle // zoomed region in screen coordinates, empty for none
+	grid    int             // grid overlay cell size, 0 for none
+	last    *Frame
+}
+
+// NewCapturer creates a capturer for a 1-based display number or
+// AllDisplays. 0 selects the primary display. Screenshots are downscaled
+// so their longer edge is at most maxEdge pixels; 0 keeps them native.
+func NewCapturer(display, maxEdge int) *Capturer {
+	return NewCapturerFrom(NewSource(), display, maxEdge)
+}
+
+// NewCapturerFrom creates a capturer like NewCapturer that grabs its
+// screenshots from src.
+func NewCapturerFrom(src Source, display, maxEdge int) *Capturer {
+	if display == 0 {
+		display = 1
+	}
+	return &Capturer{src: src, display: display, maxEdge: maxEdge}
+}
+
+// SetGrid turns on a labeled grid overlay with cells of cellSize image
+// pixels, or turns it off when cellSize is 0.
+func (c *Capturer) SetGrid(cellSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.grid = max(cellSize, 0)
+}
+
+// SetDisplay selects the display for later captures.
+func (c *Capturer) SetDisplay(display int) error {
+	if display != AllDisplays {
+		if n := len(c.src.Displays()); display < 1 || display > n {
+			return fmt.Errorf("display %d does not exist (%d displays)", display, n)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.display = display
+	c.region = image.Rectangle{}
+	return nil
+}
+
+// Zoom makes later captures show only region, given in global screen
+// coordinates, magnified to fill the usual screenshot size. The region is
+// clipped to the last captured frame.
+func (c *Capturer) Zoom(region image.Rectangle) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	region = region.Canon()
+	if c.last != nil {
+		region = region.Intersect(c.last.Bounds)
+	}
+	if region.Dx() < minZoomSize || region.Dy() < minZoomSize {
+		return fmt.Errorf("zoom region must be at least %dx%d screen pixels", minZoomSize, minZoomSize)
+	}
+	c.region = region
+	return nil
+}
+
+// ResetZoom returns later captures to the whole selected display.
+func (c *Capturer) ResetZoom() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.region = image.Rectangle{}
+}
+
+// Capture takes a screenshot of the selected display.
+func (c *Capturer) Capture() (*Frame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	frame, err := c.grab()
+	if err != nil {
+		return nil, err
+	}
+
+	native := frame.Image.Bounds().Dx()
+	if frame.Zoomed {
+		frame.Image = Upscale(frame.Image, c.zoomEdge(), maxZoomFactor)
+	} else {
+		frame.Image = Downscale(frame.Image, c.maxEdge)
+	}
+	frame.Scale = float64(frame.Image.Bounds().Dx()) / float64(native)
+
+	c.finish(frame)
+	return frame, nil
+}
+
+// grab captures the current view at native resolution.
+func (c *Capturer) grab() (*Frame, error) {
+	rects := c.src.Displays()
+	n := len(rects)
+	if n == 0 {
+		return nil, fmt.Errorf("no active displays found")
+	}
+
+	frame := &Frame{Display: c.display, Count: n, Scale: 1}
+	if !c.region.Empty() {
+		img, err := c.src.CaptureRect(c.region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture zoomed region: %w", err)
+		}
+		frame.Image, frame.Bounds, frame.Zoomed = img, c.region, true
+		return frame, nil
+	}
+
+	if c.display == AllDisplays {
+		img, bounds, err := stitch(c.src, rects)
+		if err != nil {
+			return nil, err
+		}
+		frame.Image, frame.Bounds = img, bounds
+		return frame, nil
+	}
+
+	// Fall back to the primary display if the selected one went away
+	if c.display > n {
+		c.display = 1
+		frame.Display = 1
+	}
+	bounds := rects[c.display-1]
+	img, err := c.src.CaptureRect(bounds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture display %d: %w", c.display, err)
+	}
+	frame.Image, frame.Bounds = img, bounds
+	return frame, nil
+}
+
+// finish attaches the overlay settings to a new frame and remembers it.
+func (c *Capturer) finish(frame *Frame) {
+	if c.grid > 0 {
+		grid := NewGrid(frame.Image.Bounds().Size(), c.grid)
+		frame.Grid = &grid
+	}
+	c.last = frame
+}
+
+// zoomEdge is the longer edge zoomed captures are magnified to.
+func (c *Capturer) zoomEdge() int {
+	if c.maxEdge > 0 {
+		return c.maxEdge
+	}
+	return 1280
+}
+
+// Last returns the most recent frame, or nil if nothing was captured yet.
+func (c *Capturer) Last() *Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
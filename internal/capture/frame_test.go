@@ -0,0 +1,36 @@
+package capture
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+// brokenSource has two side by side displays that cannot be captured.
+type brokenSource struct{}
+
+func (brokenSource) Displays() []image.Rectangle {
+	return []image.Rectangle{image.Rect(0, 0, 1920, 1080), image.Rect(1920, 0, 3840, 1080)}
+}
+
+func (brokenSource) CaptureRect(image.Rectangle) (*image.RGBA, error) {
+	return nil, errors.New("no access")
+}
+
+func TestCaptureErrorNamesDisplay(t *testing.T) {
+	tests := []struct {
+		display int
+		want    string
+	}{
+		{0, "display 1:"},
+		{2, "display 2:"},
+		{AllDisplays, "display 1:"},
+	}
+	for _, tt := range tests {
+		_, err := NewCapturerFrom(brokenSource{}, tt.display, 0).Capture()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("capturing display %d: error %v, want it to name %q", tt.display, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Grid is a labeled coordinate grid drawn over a screenshot. Columns are
+// lettered A, B, ... Z, AA, AB, ... from the left and rows are numbered
+// from 1 at the top, so cells read like spreadsheet references ("C4").
+type Grid struct {
+	CellSize   int         // in image pixels
+	Size       image.Point // size of the image the grid covers
+	Cols, Rows int
+}
+
+// NewGrid creates a grid covering an image of the given size.
+func NewGrid(size image.Point, cellSize int) Grid {
+	return Grid{
+		CellSize: cellSize,
+		Size:     size,
+		Cols:     (size.X + cellSize - 1) / cellSize,
+		Rows:     (size.Y + cellSize - 1) / cellSize,
+	}
+}
+
+// Label returns the reference for the cell at a 0-based column and row.
+func (g Grid) Label(col, row int) string {
+	return columnName(col) + strconv.Itoa(row+1)
+}
+
+// Cell returns the image coordinates of the centre of a cell given by its
+// reference, e.g. "C4". Cells on the right and bottom edges may be cut off
+// by the image, in which case the centre of the visible part is returned.
+func (g Grid) Cell(ref string) (x, y int, err error) {
+	ref = strings.ToUpper(strings.TrimSpace(ref))
+	split := strings.IndexFunc(ref, func(r rune) bool { return r < 'A' || r > 'Z' })
+	if split <= 0 {
+		return 0, 0, fmt.Errorf("invalid cell %q: expected a column letter and row number such as C4", ref)
+	}
+	row, err := strconv.Atoi(ref[split:])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cell %q: expected a column letter and row number such as C4", ref)
+	}
+
+	col := 0
+	for _, r := range ref[:split] {
+		col = col*26 + int(r-'A') + 1
+	}
+	col--
+	row--
+
+	if col < 0 || col >= g.Cols || row < 0 || row >= g.Rows {
+		return 0, 0, fmt.Errorf("cell %s is outside the grid (A1-%s)", ref, g.Label(g.Cols-1, g.Rows-1))
+	}
+
+	x0, y0 := col*g.CellSize, row*g.CellSize
+	x1, y1 := min(x0+g.CellSize, g.Size.X), min(y0+g.CellSize, g.Size.Y)
+	return (x0 + x1) / 2, (y0 + y1) / 2, nil
+}
+
+// Describe explains the grid to the model.
+func (g Grid) Describe() string {
+	return fmt.Sprintf("A grid of %dpx cells is drawn over it: columns A-%s from left to right, rows 1-%d from top to bottom.",
+		g.CellSize, columnName(g.Cols-1), g.Rows)
+}
+
+// columnName returns the letters for a 0-based column index.
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+var (
+	gridLineColor  = color.RGBA{255, 0, 255, 255}
+	gridLabelColor = color.RGBA{255, 255, 255, 255}
+	gridLabelBack  = color.RGBA{128, 0, 128, 255}
+	highlightColor = color.RGBA{255, 200, 0, 255}
+)
+
+// highlightWidth is the thickness of outlines drawn by DrawOutline.
+const highlightWidth = 3
+
+// DrawOutline draws a rectangle outline just outside r, so the outlined
+// content itself stays visible.
+func DrawOutline(img *image.RGBA, r image.Rectangle) {
+	outer := r.Inset(-highlightWidth).Intersect(img.Bounds())
+	for y := outer.Min.Y; y < outer.Max.Y; y++ {
+		for x := outer.Min.X; x < outer.Max.X; x++ {
+			if !image.Pt(x, y).In(r) {
+				img.SetRGBA(x, y, highlightColor)
+			}
+		}
+	}
+}
+
+// DrawGrid draws the grid lines and cell labels onto img.
+func DrawGrid(img *image.RGBA, g Grid) {
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x += g.CellSize {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			blend(img, x, y, gridLineColor)
+		}
+	}
+	for y := b.Min.Y; y < b.Max.Y; y += g.CellSize {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			blend(img, x, y, gridLineColor)
+		}
+	}
+
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			at := image.Pt(b.Min.X+col*g.CellSize+2, b.Min.Y+row*g.CellSize+2)
+			drawLabel(img, at, g.Label(col, row))
+		}
+	}
+}
+
+// blend mixes c half and half into the pixel at x, y.
+func blend(img *image.RGBA, x, y int, c color.RGBA) {
+	o := img.PixOffset(x, y)
+	img.Pix[o] = uint8((uint16(img.Pix[o]) + uint16(c.R)) / 2)
+	img.Pix[o+1] = uint8((uint16(img.Pix[o+1]) + uint16(c.G)) / 2)
+	img.Pix[o+2] = uint8((uint16(img.Pix[o+2]) + uint16(c.B)) / 2)
+}
+
+// labelScale enlarges the 3x5 glyphs so labels survive downscaling and
+// JPEG compression.
+const labelScale = 2
+
+// drawLabel draws text with its top-left corner at at, on a solid
+// background so it stays readable over any content.
+func drawLabel(img *image.RGBA, at image.Point, text string) {
+	glyphW, glyphH := 3*labelScale, 5*labelScale
+	box := image.Rect(at.X, at.Y, at.X+len(text)*(glyphW+labelScale)+labelScale, at.Y+glyphH+2*labelScale)
+	box = box.Intersect(img.Bounds())
+	for y := box.Min.Y; y < box.Max.Y; y++ {
+		for x := box.Min.X; x < box.Max.X; x++ {
+			img.SetRGBA(x, y, gridLabelBack)
+		}
+	}
+
+	x := at.X + labelScale
+	for _, r := range text {
+		rows := font3x5[r]
+		for gy, bits := range rows {
+			for gx := 0; gx < 3; gx++ {
+				if bits&(4>>gx) == 0 {
+					continue
+				}
+				for sy := 0; sy < labelScale; sy++ {
+					for sx := 0; sx < labelScale; sx++ {
+						p := image.Pt(x+gx*labelScale+sx, at.Y+labelScale+gy*labelScale+sy)
+						if p.In(img.Bounds()) {
+							img.SetRGBA(p.X, p.Y, gridLabelColor)
+						}
+					}
+				}
+			}
+		}
+		x += glyphW + labelScale
+	}
+}
+
+// font3x5 is a minimal bitmap font for grid labels. Each glyph is five rows
+// of three bits, most significant bit on the left.
+var font3x5 = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'A': {2, 5, 7, 5, 5},
+	'B': {6, 5, 6, 5, 6},
+	'C': {3, 4, 4, 4, 3},
+	'D': {6, 5, 5, 5, 6},
+	'E': {7, 4, 6, 4, 7},
+	'F': {7, 4, 6, 4, 4},
+	'G': {3, 4, 5, 5, 3},
+	'H': {5, 5, 7, 5, 5},
+	'I': {7, 2, 2, 2, 7},
+	'J': {1, 1, 1, 5, 2},
+	'K': {5, 5, 6, 5, 5},
+	'L': {4, 4, 4, 4, 7},
+	'M': {5, 7, 7, 5, 5},
+	'N': {6, 5, 5, 5, 5},
+	'O': {2, 5, 5, 5, 2},
+	'P': {6, 5, 6, 4, 4},
+	'Q': {2, 5, 5, 6, 3},
+	'R': {6, 5, 6, 5, 5},
+	'S': {3, 4, 2, 1, 6},
+	'T': {7, 2, 2, 2, 2},
+	'U': {5, 5, 5, 5, 7},
+	'V': {5, 5, 5, 5, 2},
+	'W': {5, 5, 7, 7, 5},
+	'X': {5, 5, 2, 5, 5},
+	'Y': {5, 5, 2, 2, 2},
+	'Z': {7, 1, 2, 4, 7},
+}
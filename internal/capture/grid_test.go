@@ -0,0 +1,135 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewGridCoversImage(t *testing.T) {
+	tests := []struct {
+		size       image.Point
+		cell       int
+		cols, rows int
+	}{
+		{image.Pt(1280, 720), 100, 13, 8},
+		{image.Pt(1200, 700), 100, 12, 7},
+		{image.Pt(1201, 701), 100, 13, 8},
+		{image.Pt(50, 50), 100, 1, 1},
+	}
+	for _, tt := range tests {
+		g := NewGrid(tt.size, tt.cell)
+		if g.Cols != tt.cols || g.Rows != tt.rows {
+			t.Errorf("NewGrid(%v, %d) = %dx%d cells, want %dx%d", tt.size, tt.cell, g.Cols, g.Rows, tt.cols, tt.rows)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	tests := map[int]string{
+		0:   "A",
+		2:   "C",
+		25:  "Z",
+		26:  "AA",
+		27:  "AB",
+		51:  "AZ",
+		52:  "BA",
+		701: "ZZ",
+		702: "AAA",
+	}
+	for col, want := range tests {
+		if got := columnName(col); got != want {
+			t.Errorf("columnName(%d) = %q, want %q", col, got, want)
+		}
+	}
+}
+
+func TestGridCell(t *testing.T) {
+	g := NewGrid(image.Pt(1280, 720), 100)
+	tests := []struct {
+		ref     string
+		x, y    int
+		wantErr bool
+	}{
+		{"A1", 50, 50, false},
+		{"C4", 250, 350, false},
+		{"c4", 250, 350, false},
+		{" B2 ", 150, 150, false},
+		{"M8", 1240, 710, false}, // cut off on the right and bottom edges
+		{"N1", 0, 0, true},       // beyond the last column
+		{"A9", 0, 0, true},       // beyond the last row
+		{"A0", 0, 0, true},
+		{"4C", 0, 0, true},
+		{"C", 0, 0, true},
+		{"", 0, 0, true},
+	}
+	for _, tt := range tests {
+		x, y, err := g.Cell(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Cell(%q) error = %v, want error %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (x != tt.x || y != tt.y) {
+			t.Errorf("Cell(%q) = (%d, %d), want (%d, %d)", tt.ref, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestGridLabelRoundTrip(t *testing.T) {
+	g := NewGrid(image.Pt(3000, 400), 100) // 30 columns, past Z
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			x, y, err := g.Cell(g.Label(col, row))
+			if err != nil {
+				t.Fatalf("Cell(%q): %v", g.Label(col, row), err)
+			}
+			if x/g.CellSize != col || y/g.CellSize != row {
+				t.Errorf("Cell(%q) = (%d, %d), outside column %d row %d", g.Label(col, row), x, y, col, row)
+			}
+		}
+	}
+}
+
+func TestDrawGrid(t *testing.T) {
+	back := color.RGBA{0, 0, 0, 255}
+	img := solid(300, 200, back)
+	g := NewGrid(img.Bounds().Size(), 100)
+	DrawGrid(img, g)
+
+	// Lines are blended half and half with the line color
+	line := color.RGBA{127, 0, 127, 255}
+	for _, p := range []image.Point{{100, 50}, {200, 150}, {150, 100}, {250, 0}, {0, 150}} {
+		if c := img.RGBAAt(p.X, p.Y); c != line {
+			t.Errorf("line pixel %v = %v, want %v", p, c, line)
+		}
+	}
+	// Each cell gets a label background in its top-left corner
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			p := image.Pt(col*100+3, row*100+3)
+			if c := img.RGBAAt(p.X, p.Y); c != gridLabelBack && c != gridLabelColor {
+				t.Errorf("label of %s at %v = %v, want label colors", g.Label(col, row), p, c)
+			}
+		}
+	}
+	// Cell interiors are left alone
+	for _, p := range []image.Point{{50, 50}, {150, 150}, {299, 199}} {
+		if c := img.RGBAAt(p.X, p.Y); c != back {
+			t.Errorf("interior pixel %v = %v, want untouched", p, c)
+		}
+	}
+}
+
+func TestAnnotatedLeavesImage(t *testing.T) {
+	img := solid(200, 100, color.RGBA{0, 0, 0, 255})
+	grid := NewGrid(img.Bounds().Size(), 50)
+	f := &Frame{Image: img, Bounds: img.Bounds(), Grid: &grid}
+
+	annotated := f.Annotated()
+	if annotated == img {
+		t.Fatal("grid drawn onto the captured image")
+	}
+	if c := img.RGBAAt(0, 0); c != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("captured image changed to %v", c)
+	}
+}
@@ -0,0 +1,104 @@
+package capture
+
+import (
+	"image"
+	"math"
+)
+
+// Downscale shrinks img so that its longer edge is at most maxEdge pixels,
+// keeping the aspect ratio. Each output pixel is the average of the source
+// pixels it covers, which keeps thin lines and small text legible. Images
+// that already fit, and a maxEdge of 0 or less, are returned unchanged.
+func Downscale(img *image.RGBA, maxEdge int) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if maxEdge <= 0 || (srcW <= maxEdge && srcH <= maxEdge) {
+		return img
+	}
+
+	scale := float64(maxEdge) / float64(max(srcW, srcH))
+	dstW := max(int(math.Round(float64(srcW)*scale)), 1)
+	dstH := max(int(math.Round(float64(srcH)*scale)), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for dy := 0; dy < dstH; dy++ {
+		y0, y1 := span(dy, dstH, srcH)
+		for dx := 0; dx < dstW; dx++ {
+			x0, x1 := span(dx, dstW, srcW)
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride+x0*4 : sy*img.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint32(row[i])
+					g += uint32(row[i+1])
+					bl += uint32(row[i+2])
+					a += uint32(row[i+3])
+					n++
+				}
+			}
+
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span returns the source pixel range [lo, hi) covered by output pixel i
+// when mapping src pixels onto dst pixels. The range is never empty.
+func span(i, dst, src int) (int, int) {
+	lo := i * src / dst
+	hi := (i + 1) * src / dst
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return lo, hi
+}
+
+// Upscale enlarges img so that its longer edge is maxEdge pixels, by at
+// most maxFactor, using bilinear interpolation. Images that are already
+// large enough are returned unchanged.
+func Upscale(img *image.RGBA, maxEdge int, maxFactor float64) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	factor := min(float64(maxEdge)/float64(max(srcW, srcH)), maxFactor)
+	if srcW == 0 || srcH == 0 || factor <= 1 {
+		return img
+	}
+
+	dstW := int(math.Round(float64(srcW) * factor))
+	dstH := int(math.Round(float64(srcH) * factor))
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for dy := 0; dy < dstH; dy++ {
+		// Sample at the centre of the output pixel
+		fy := max((float64(dy)+0.5)*float64(srcH)/float64(dstH)-0.5, 0)
+		y0 := min(int(fy), srcH-1)
+		y1 := min(y0+1, srcH-1)
+		wy := fy - float64(y0)
+
+		for dx := 0; dx < dstW; dx++ {
+			fx := max((float64(dx)+0.5)*float64(srcW)/float64(dstW)-0.5, 0)
+			x0 := min(int(fx), srcW-1)
+			x1 := min(x0+1, srcW-1)
+			wx := fx - float64(x0)
+
+			p00 := img.Pix[y0*img.Stride+x0*4:]
+			p01 := img.Pix[y0*img.Stride+x1*4:]
+			p10 := img.Pix[y1*img.Stride+x0*4:]
+			p11 := img.Pix[y1*img.Stride+x1*4:]
+
+			o := dst.PixOffset(dx, dy)
+			for c := 0; c < 4; c++ {
+				top := float64(p00[c])*(1-wx) + float64(p01[c])*wx
+				bottom := float64(p10[c])*(1-wx) + float64(p11[c])*wx
+				dst.Pix[o+c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,177 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// solid returns a w x h image filled with c.
+func solid(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestDownscaleSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxEdge int
+		wantW, wantH  int
+	}{
+		{1920, 1080, 1280, 1280, 720},
+		{2560, 1440, 1280, 1280, 720},
+		{1080, 1920, 1280, 720, 1280},
+		{3840, 1080, 1280, 1280, 360},
+		{1366, 768, 1280, 1280, 720},
+		{1281, 720, 1280, 1280, 719},
+		{5000, 1, 100, 100, 1}, // never rounds to zero
+		{1280, 720, 1280, 1280, 720},
+		{800, 600, 1280, 800, 600},
+		{1920, 1080, 0, 1920, 1080},
+	}
+	for _, tt := range tests {
+		img := solid(tt.w, tt.h, color.RGBA{A: 255})
+		got := Downscale(img, tt.maxEdge).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("Downscale(%dx%d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.maxEdge, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestDownscaleKeepsFittingImage(t *testing.T) {
+	img := solid(640, 480, color.RGBA{A: 255})
+	if Downscale(img, 640) != img {
+		t.Error("an image that fits was copied")
+	}
+}
+
+func TestDownscaleAverages(t *testing.T) {
+	// Alternating black and white columns average to grey
+	img := solid(4, 2, color.RGBA{A: 255})
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x += 2 {
+			img.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	got := Downscale(img, 2)
+	if got.Bounds().Dx() != 2 || got.Bounds().Dy() != 1 {
+		t.Fatalf("size = %v, want 2x1", got.Bounds().Size())
+	}
+	for x := 0; x < 2; x++ {
+		if c := got.RGBAAt(x, 0); c != (color.RGBA{127, 127, 127, 255}) {
+			t.Errorf("pixel %d = %v, want grey", x, c)
+		}
+	}
+}
+
+func TestUpscaleSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxEdge int
+		maxFactor     float64
+		wantW, wantH  int
+	}{
+		{320, 180, 1280, 4, 1280, 720},
+		{100, 50, 1280, 4, 400, 200}, // capped at 4x
+		{640, 360, 1280, 4, 1280, 720},
+		{1280, 720, 1280, 4, 1280, 720}, // already large enough
+		{2000, 1000, 1280, 4, 2000, 1000},
+	}
+	for _, tt := range tests {
+		got := Upscale(solid(tt.w, tt.h, color.RGBA{A: 255}), tt.maxEdge, tt.maxFactor).Bounds()
+		if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+			t.Errorf("Upscale(%dx%d, %d, %g) = %dx%d, want %dx%d", tt.w, tt.h, tt.maxEdge, tt.maxFactor, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestUpscaleKeepsColors(t *testing.T) {
+	c := color.RGBA{10, 200, 30, 255}
+	got := Upscale(solid(20, 20, c), 80, 4)
+	for _, p := range []image.Point{{0, 0}, {79, 0}, {0, 79}, {79, 79}, {40, 40}} {
+		if px := got.RGBAAt(p.X, p.Y); px != c {
+			t.Errorf("pixel %v = %v, want %v", p, px, c)
+		}
+	}
+}
+
+// frames covers the ways an image can relate to the screen area it shows.
+var frames = []struct {
+	name   string
+	bounds image.Rectangle
+	img    image.Point
+}{
+	{"native", image.Rect(0, 0, 1280, 720), image.Pt(1280, 720)},
+	{"half", image.Rect(0, 0, 2560, 1440), image.Pt(1280, 720)},
+	{"two thirds", image.Rect(0, 0, 1920, 1080), image.Pt(1280, 720)},
+	{"odd ratio", image.Rect(0, 0, 1366, 768), image.Pt(1280, 720)},
+	{"second display", image.Rect(1920, 0, 3840, 1080), image.Pt(1280, 720)},
+	{"left of primary", image.Rect(-1920, 200, 0, 1280), image.Pt(1280, 720)},
+	{"zoomed", image.Rect(500, 300, 820, 480), image.Pt(1280, 720)},
+}
+
+func frameFor(bounds image.Rectangle, size image.Point) *Frame {
+	return &Frame{Image: image.NewRGBA(image.Rectangle{Max: size}), Bounds: bounds}
+}
+
+func TestToScreenStaysInBounds(t *testing.T) {
+	for _, tt := range frames {
+		f := frameFor(tt.bounds, tt.img)
+		corners := []image.Point{{0, 0}, {tt.img.X - 1, 0}, {0, tt.img.Y - 1}, {tt.img.X - 1, tt.img.Y - 1}}
+		for _, p := range corners {
+			x, y := f.ToScreen(p.X, p.Y)
+			if !image.Pt(x, y).In(tt.bounds) {
+				t.Errorf("%s: image corner %v maps to (%d, %d), outside %v", tt.name, p, x, y, tt.bounds)
+			}
+		}
+	}
+}
+
+func TestImageScreenRoundTrip(t *testing.T) {
+	for _, tt := range frames {
+		f := frameFor(tt.bounds, tt.img)
+		if tt.img.X <= tt.bounds.Dx() {
+			// Every image pixel maps to a screen pixel that maps back to it
+			for x := 0; x < tt.img.X; x++ {
+				for _, y := range []int{0, tt.img.Y / 2, tt.img.Y - 1} {
+					sx, sy := f.ToScreen(x, y)
+					if bx, by := f.FromScreen(sx, sy); bx != x || by != y {
+						t.Fatalf("%s: (%d, %d) -> (%d, %d) -> (%d, %d)", tt.name, x, y, sx, sy, bx, by)
+					}
+				}
+			}
+		} else {
+			// Magnified: every screen pixel maps to an image pixel that maps back to it
+			for x := tt.bounds.Min.X; x < tt.bounds.Max.X; x++ {
+				for _, y := range []int{tt.bounds.Min.Y, tt.bounds.Max.Y - 1} {
+					ix, iy := f.FromScreen(x, y)
+					if sx, sy := f.ToScreen(ix, iy); sx != x || sy != y {
+						t.Fatalf("%s: (%d, %d) -> (%d, %d) -> (%d, %d)", tt.name, x, y, ix, iy, sx, sy)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDownscaledClickHitsTarget(t *testing.T) {
+	// A white button on a black 1920x1080 screen, found in the downscaled
+	// screenshot and clicked through ToScreen, is hit on screen
+	screen := solid(1920, 1080, color.RGBA{A: 255})
+	button := image.Rect(1500, 900, 1530, 920)
+	for y := button.Min.Y; y < button.Max.Y; y++ {
+		for x := button.Min.X; x < button.Max.X; x++ {
+			screen.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+
+	f := &Frame{Image: Downscale(screen, 1280), Bounds: screen.Bounds()}
+	ix, iy := f.FromScreen(1515, 910)
+	if c := f.Image.RGBAAt(ix, iy); c.R < 200 {
+		t.Fatalf("button centre maps to (%d, %d), which is %v in the screenshot", ix, iy, c)
+	}
+	if x, y := f.ToScreen(ix, iy); !image.Pt(x, y).In(button) {
+		t.Errorf("click at (%d, %d) in the screenshot lands at (%d, %d), outside %v", ix, iy, x, y, button)
+	}
+}
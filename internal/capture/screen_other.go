@@ -0,0 +1,30 @@
+//go:build !linux
+
+package capture
+
+import (
+	"image"
+
+	"github.com/kbinani/screenshot"
+)
+
+// screenSource captures through kbinani/screenshot.
+type screenSource struct{}
+
+// NewSource returns the source for the platform's screen.
+func NewSource() Source {
+	return screenSource{}
+}
+
+func (screenSource) Displays() []image.Rectangle {
+	n := screenshot.NumActiveDisplays()
+	rects := make([]image.Rectangle, n)
+	for i := range rects {
+		rects[i] = screenshot.GetDisplayBounds(i)
+	}
+	return rects
+}
+
+func (screenSource) CaptureRect(bounds image.Rectangle) (*image.RGBA, error) {
+	return screenshot.CaptureRect(bounds)
+}
@@ -0,0 +1,130 @@
+//go:build linux
+
+package capture
+
+import (
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xinerama"
+	"github.com/jezek/xgb/xproto"
+)
+
+// On Linux we talk to the X server directly rather than going through
+// kbinani/screenshot, which requires an active Xinerama layout and so
+// reports zero displays on a default Xvfb.
+
+// x11Source captures over one X connection, opened on first use and
+// reopened after a request on it fails. WaitStable polls the screen
+// several times a second, so connecting per call is too slow.
+type x11Source struct {
+	mu       sync.Mutex
+	conn     *xgb.Conn
+	xinerama bool // the Xinerama extension is available on conn
+}
+
+// NewSource returns the source for the X display named by $DISPLAY.
+func NewSource() Source {
+	return &x11Source{}
+}
+
+// connect returns the open connection, connecting if needed.
+func (s *x11Source) connect() (*xgb.Conn, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := xgb.NewConn()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to connect to X server: %w", err)
+		}
+		s.conn = conn
+		s.xinerama = xinerama.Init(conn) == nil
+	}
+	return s.conn, s.xinerama, nil
+}
+
+// drop closes conn after a failed request so the next call reconnects.
+func (s *x11Source) drop(conn *xgb.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		conn.Close()
+		s.conn = nil
+	}
+}
+
+// Displays returns the bounds of each monitor in root window coordinates.
+// Without Xinerama the whole root window is reported as a single display.
+func (s *x11Source) Displays() []image.Rectangle {
+	conn, hasXinerama, err := s.connect()
+	if err != nil {
+		return nil
+	}
+
+	root := xproto.Setup(conn).DefaultScreen(conn)
+	whole := []image.Rectangle{image.Rect(0, 0, int(root.WidthInPixels), int(root.HeightInPixels))}
+	if !hasXinerama {
+		return whole
+	}
+	active, err := xinerama.IsActive(conn).Reply()
+	if err != nil {
+		s.drop(conn)
+		return whole
+	}
+	if active.State == 0 {
+		return whole
+	}
+	reply, err := xinerama.QueryScreens(conn).Reply()
+	if err != nil || reply.Number == 0 {
+		return whole
+	}
+
+	rects := make([]image.Rectangle, len(reply.ScreenInfo))
+	for i, info := range reply.ScreenInfo {
+		rects[i] = image.Rect(int(info.XOrg), int(info.YOrg), int(info.XOrg)+int(info.Width), int(info.YOrg)+int(info.Height))
+	}
+	return rects
+}
+
+// CaptureRect grabs a rectangle of the root window with XGetImage.
+func (s *x11Source) CaptureRect(bounds image.Rectangle) (*image.RGBA, error) {
+	conn, _, err := s.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	setup := xproto.Setup(conn)
+	screen := setup.DefaultScreen(conn)
+
+	bpp := 0
+	for _, f := range setup.PixmapFormats {
+		if f.Depth == screen.RootDepth {
+			bpp = int(f.BitsPerPixel)
+		}
+	}
+	if bpp != 32 {
+		return nil, fmt.Errorf("unsupported X visual: depth %d, %d bits per pixel", screen.RootDepth, bpp)
+	}
+
+	reply, err := xproto.GetImage(conn, xproto.ImageFormatZPixmap, xproto.Drawable(screen.Root),
+		int16(bounds.Min.X), int16(bounds.Min.Y), uint16(bounds.Dx()), uint16(bounds.Dy()), 0xffffffff).Reply()
+	if err != nil {
+		s.drop(conn)
+		return nil, fmt.Errorf("XGetImage failed: %w", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	msbFirst := setup.ImageByteOrder == xproto.ImageOrderMSBFirst
+	for i := 0; i+3 < len(reply.Data) && i < len(img.Pix); i += 4 {
+		p := reply.Data[i : i+4]
+		if msbFirst {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2] = p[1], p[2], p[3]
+		} else {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2] = p[2], p[1], p[0]
+		}
+		img.Pix[i+3] = 255
+	}
+	return img, nil
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package capture
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// These tests run against a real X server and are skipped without one.
+// make test-xvfb runs them under Xvfb.
+
+func requireDisplay(t *testing.T) {
+	t.Helper()
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY is not set; run under Xvfb with make test-xvfb")
+	}
+}
+
+func TestX11SourceCapture(t *testing.T) {
+	requireDisplay(t)
+
+	src := NewSource().(*x11Source)
+	rects := src.Displays()
+	if len(rects) == 0 {
+		t.Fatal("no displays reported")
+	}
+	img, err := src.CaptureRect(rects[0])
+	if err != nil {
+		t.Fatalf("CaptureRect: %v", err)
+	}
+	if got := img.Bounds().Size(); got != rects[0].Size() {
+		t.Errorf("captured %v, want %v", got, rects[0].Size())
+	}
+}
+
+func TestX11CapturerReusesConnection(t *testing.T) {
+	requireDisplay(t)
+
+	src := NewSource().(*x11Source)
+	c := NewCapturerFrom(src, 1, 640)
+	frame, err := c.Capture()
+	if err != nil {
+		t.Fatalf("Capture: %v", err)
+	}
+	if frame.Bounds != src.Displays()[0] {
+		t.Errorf("frame bounds %v, want display 1 at %v", frame.Bounds, src.Displays()[0])
+	}
+	if edge := max(frame.Image.Bounds().Dx(), frame.Image.Bounds().Dy()); edge > 640 {
+		t.Errorf("longer edge %d, want at most 640", edge)
+	}
+
+	conn := src.conn
+	if _, err := c.WaitStable(context.Background(), 200*time.Millisecond, time.Second); err != nil {
+		t.Fatalf("WaitStable: %v", err)
+	}
+	if src.conn != conn {
+		t.Error("capturer opened a new X connection while polling")
+	}
+}
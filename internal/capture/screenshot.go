@@ -7,13 +7,14 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	"image/png"
 
-	"github.com/kbinani/screenshot"
+	"github.com/thesimpledev/golemming/internal/config"
 )
 
 // CaptureAll captures the primary display and returns the image.
 func CaptureAll() (*image.RGBA, error) {
-	n := screenshot.NumActiveDisplays()
+	n := numDisplays()
 	if n == 0 {
 		return nil, fmt.Errorf("no active displays found")
 	}
@@ -22,13 +23,13 @@ func CaptureAll() (*image.RGBA, error) {
 
 // CaptureDisplay captures a specific display by index.
 func CaptureDisplay(index int) (*image.RGBA, error) {
-	n := screenshot.NumActiveDisplays()
+	n := numDisplays()
 	if index < 0 || index >= n {
 		return nil, fmt.Errorf("display index %d out of range (0-%d)", index, n-1)
 	}
 
-	bounds := screenshot.GetDisplayBounds(index)
-	img, err := screenshot.CaptureRect(bounds)
+	bounds := displayBounds(index)
+	img, err := captureRect(bounds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture display %d: %w", index, err)
 	}
@@ -36,42 +37,56 @@ func CaptureDisplay(index int) (*image.RGBA, error) {
 	return img, nil
 }
 
-// EncodeToBase64JPEG encodes an image to base64 JPEG with specified quality.
-func EncodeToBase64JPEG(img image.Image, quality int) (string, error) {
-	if quality <= 0 || quality > 100 {
-		quality = 80
-	}
+// Encoded is an image encoded for sending to the model.
+type Encoded struct {
+	Data      string // base64 encoded
+	MediaType string
+	Size      int // encoded size in bytes, before base64
+}
 
+// Encode encodes an image in a config.Format* format, as JPEG with the
+// given quality or as PNG.
+func Encode(img image.Image, format string, quality int) (*Encoded, error) {
 	var buf bytes.Buffer
-	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
-	if err != nil {
-		return "", fmt.Errorf("failed to encode JPEG: %w", err)
+	var mediaType string
+	switch format {
+	case config.FormatJPEG, "":
+		if quality <= 0 || quality > 100 {
+			quality = 80
+		}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+		mediaType = "image/jpeg"
+	case config.FormatPNG:
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		if err := enc.Encode(&buf, img); err != nil {
+			return nil, fmt.Errorf("failed to encode PNG: %w", err)
+		}
+		mediaType = "image/png"
+	default:
+		return nil, fmt.Errorf("unsupported image format %q", format)
 	}
 
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
-}
-
-// CaptureAndEncode captures the primary display and returns it as base64 JPEG.
-func CaptureAndEncode() (string, error) {
-	img, err := CaptureAll()
-	if err != nil {
-		return "", err
-	}
-	return EncodeToBase64JPEG(img, 80)
+	return &Encoded{
+		Data:      base64.StdEncoding.EncodeToString(buf.Bytes()),
+		MediaType: mediaType,
+		Size:      buf.Len(),
+	}, nil
 }
 
 // GetDisplayCount returns the number of active displays.
 func GetDisplayCount() int {
-	return screenshot.NumActiveDisplays()
+	return numDisplays()
 }
 
 // GetDisplayBounds returns the bounds of a display.
 func GetDisplayBounds(index int) (x, y, width, height int, err error) {
-	n := screenshot.NumActiveDisplays()
+	n := numDisplays()
 	if index < 0 || index >= n {
 		return 0, 0, 0, 0, fmt.Errorf("display index %d out of range (0-%d)", index, n-1)
 	}
 
-	bounds := screenshot.GetDisplayBounds(index)
+	bounds := displayBounds(index)
 	return bounds.Min.X, bounds.Min.Y, bounds.Dx(), bounds.Dy(), nil
 }
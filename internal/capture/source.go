@@ -0,0 +1,33 @@
+package capture
+
+import "image"
+
+// Source grabs pixels from a desktop. The platform source talks to the
+// screen; tests can supply synthetic images instead.
+type Source interface {
+	// Displays returns the bounds of each display in global screen
+	// coordinates, the primary display first. It is empty when no display
+	// is available.
+	Displays() []image.Rectangle
+	// CaptureRect grabs an area given in global screen coordinates.
+	CaptureRect(bounds image.Rectangle) (*image.RGBA, error)
+}
+
+// desktop is the source behind the package level capture functions.
+var desktop = NewSource()
+
+func numDisplays() int {
+	return len(desktop.Displays())
+}
+
+func displayBounds(index int) image.Rectangle {
+	rects := desktop.Displays()
+	if index < 0 || index >= len(rects) {
+		return image.Rectangle{}
+	}
+	return rects[index]
+}
+
+func captureRect(bounds image.Rectangle) (*image.RGBA, error) {
+	return desktop.CaptureRect(bounds)
+}
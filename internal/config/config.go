@@ -6,34 +6,152 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 )
 
-// Config holds the application configuration.
+// Supported LLM providers
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai" // Any OpenAI-compatible chat completions API
+	ProviderScript    = "script" // Replays actions from ScriptPath, no model involved
+)
+
+// Supported screenshot encodings
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png" // Lossless, larger but keeps small text crisp
+)
+
+// Verification modes
+const (
+	VerifyOff      = ""
+	VerifyPostTask = "post_task" // review once the agent reports done
+	VerifyPerStep  = "per_step"  // review after every mouse, keyboard or file_write action
+)
+
+// Approval policies
+const (
+	ApprovalNever     = ""
+	ApprovalAlways    = "always"
+	ApprovalFileWrite = "file_write" // only file writes
+	ApprovalKeys      = "keys"       // only keys matching ApprovalKeyPattern
+)
+
+// Config holds the application configuration. Settings whose zero value
+// means something, such as no retries or no timeout, are saved even when
+// zero, so that loading the file does not replace them with the default.
 type Config struct {
 	// API settings
-	APIKey string `json:"api_key,omitempty"`
-	Model  string `json:"model,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	BaseURL  string `json:"base_url,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+	Model    string `json:"model,omitempty"`
+
+	// Connection settings
+	TimeoutSeconds int    `json:"timeout_seconds"` // 0 for no timeout
+	Proxy          string `json:"proxy,omitempty"` // e.g. http://proxy.example:3128
+
+	// Retry settings for transient API failures (429, 5xx, network errors)
+	MaxRetries         int `json:"max_retries"`
+	RetryBaseMs        int `json:"retry_base_ms"`
+	RetryBudgetSeconds int `json:"retry_budget_seconds"`
+
+	// ScriptPath is the action script replayed by the script provider
+	ScriptPath string `json:"script_path,omitempty"`
 
 	// Agent settings
 	MaxIterations     int `json:"max_iterations,omitempty"`
-	StabilizationMs   int `json:"stabilization_ms,omitempty"`
-	DefaultWaitMs     int `json:"default_wait_ms,omitempty"`
-	ScreenshotQuality int `json:"screenshot_quality,omitempty"`
+	DefaultWaitMs     int `json:"default_wait_ms"`
+	ScreenshotQuality int `json:"screenshot_quality,omitempty"` // JPEG quality, 1-100
+
+	// After each action the agent waits until the screen has been unchanged
+	// for StabilizationMs, giving up after StabilizationTimeoutMs. After an
+	// action that changed nothing it keeps watching for a late change until
+	// StabilizationTimeoutMs before asking the model again.
+	// StabilizationMs used to be a fixed sleep after every action; saved
+	// values keep working and now give the quiet period to wait for, so a
+	// settled screen still waits at least as long as before.
+	StabilizationMs        int `json:"stabilization_ms"`
+	StabilizationTimeoutMs int `json:"stabilization_timeout_ms"`
+
+	// ScreenshotFormat is the encoding of screenshots sent to the model
+	ScreenshotFormat string `json:"screenshot_format,omitempty"`
+
+	// Display is the display captured when a run starts: 1-based, 0 for
+	// the primary display or -1 for all displays stitched together.
+	Display int `json:"display"`
+
+	// MaxScreenshotEdge is the longest edge, in pixels, of the screenshots
+	// sent to the model. Larger captures are downscaled and clicks are
+	// mapped back to physical pixels.
+	MaxScreenshotEdge int `json:"max_screenshot_edge"`
+
+	// GridOverlay draws a labeled grid of GridCellSize pixel cells over
+	// screenshots so the model can click by cell reference.
+	GridOverlay  bool `json:"grid_overlay,omitempty"`
+	GridCellSize int  `json:"grid_cell_size,omitempty"`
+
+	// HighlightChanges outlines the area that changed since the previous
+	// step on the screenshot sent to the model.
+	HighlightChanges bool `json:"highlight_changes,omitempty"`
+
+	// MaxCorrections is how many times an unparseable or invalid model
+	// response is sent back for correction before the step fails.
+	MaxCorrections int `json:"max_corrections"`
+
+	// Conversation settings. In conversation mode the model sees its earlier
+	// turns and the last ImageWindow screenshots instead of a fresh message
+	// per step.
+	ConversationMode bool `json:"conversation_mode,omitempty"`
+	ImageWindow      int  `json:"image_window,omitempty"`
+
+	// Verify has a verifier agent review the work and send the agent back
+	// with its feedback on rejection. The verifier uses the agent's provider
+	// and model unless the Verify* settings below choose its own; with the
+	// script provider it replays VerifyScriptPath, never the agent's script.
+	Verify           string `json:"verify,omitempty"`
+	VerifyProvider   string `json:"verify_provider,omitempty"`
+	VerifyBaseURL    string `json:"verify_base_url,omitempty"`
+	VerifyAPIKey     string `json:"verify_api_key,omitempty"`
+	VerifyModel      string `json:"verify_model,omitempty"`
+	VerifyScriptPath string `json:"verify_script_path,omitempty"`
+
+	// Session recording. Each run is written to its own directory under
+	// SessionDir, or ~/.golemming/sessions when empty.
+	RecordSessions bool   `json:"record_sessions,omitempty"`
+	SessionDir     string `json:"session_dir,omitempty"`
 
 	// Safety settings
-	RequireAbsolutePaths bool `json:"require_absolute_paths,omitempty"`
+	RequireAbsolutePaths bool `json:"require_absolute_paths"`
+
+	// Approval selects the actions the operator must approve before they
+	// run. ApprovalKeyPattern is a regular expression matched against key
+	// names, e.g. "^(alt\\+f4|ctrl\\+w)$", for the keys policy.
+	Approval           string `json:"approval,omitempty"`
+	ApprovalKeyPattern string `json:"approval_key_pattern,omitempty"`
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
-		Model:                "claude-sonnet-4-20250514",
-		MaxIterations:        100,
-		StabilizationMs:      500,
-		DefaultWaitMs:        500,
-		ScreenshotQuality:    80,
-		RequireAbsolutePaths: true,
+		Provider:               ProviderAnthropic,
+		Model:                  "claude-sonnet-4-20250514",
+		TimeoutSeconds:         120,
+		MaxRetries:             5,
+		RetryBaseMs:            1000,
+		RetryBudgetSeconds:     300,
+		MaxIterations:          100,
+		StabilizationMs:        500,
+		StabilizationTimeoutMs: 5000,
+		DefaultWaitMs:          500,
+		ScreenshotQuality:      80,
+		ScreenshotFormat:       FormatJPEG,
+		MaxScreenshotEdge:      1280,
+		GridCellSize:           100,
+		MaxCorrections:         2,
+		ImageWindow:            3,
+		RequireAbsolutePaths:   true,
 	}
 }
 
@@ -55,9 +173,19 @@ func ConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
-// Load loads configuration from file and environment variables.
-// Environment variables take precedence over file settings.
+// Load loads configuration from file and environment variables and
+// validates it. Environment variables take precedence over file settings.
 func Load() (*Config, error) {
+	cfg := Read()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Read loads configuration from file and environment variables without
+// validating it, so callers can apply command line overrides first.
+func Read() *Config {
 	cfg := DefaultConfig()
 
 	// Try to load from config file
@@ -69,20 +197,122 @@ func Load() (*Config, error) {
 	}
 
 	// Environment variables override file settings
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+	if provider := os.Getenv("GOLEMMING_PROVIDER"); provider != "" {
+		cfg.Provider = provider
+	}
+
+	if baseURL := os.Getenv("GOLEMMING_BASE_URL"); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+
+	if apiKey := os.Getenv(apiKeyVar(cfg.Provider)); apiKey != "" {
 		cfg.APIKey = apiKey
 	}
+	if cfg.VerifyProvider != "" && cfg.VerifyProvider != cfg.Provider {
+		if apiKey := os.Getenv(apiKeyVar(cfg.VerifyProvider)); apiKey != "" {
+			cfg.VerifyAPIKey = apiKey
+		}
+	}
 
 	if model := os.Getenv("GOLEMMING_MODEL"); model != "" {
 		cfg.Model = model
 	}
 
-	// Validate required fields
-	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY not set (set environment variable or run 'golemming' to configure)")
+	return cfg
+}
+
+// apiKeyVar is the environment variable holding the API key for provider.
+func apiKeyVar(provider string) string {
+	if provider == ProviderOpenAI {
+		return "OPENAI_API_KEY"
+	}
+	return "ANTHROPIC_API_KEY"
+}
+
+// VerifierConfig returns the configuration for the verifier's model: a
+// copy of c with the provider settings replaced by the Verify* ones. A
+// different provider inherits none of the agent's provider settings.
+func (c *Config) VerifierConfig() *Config {
+	v := *c
+	if c.VerifyProvider != "" && c.VerifyProvider != c.Provider {
+		v.Provider = c.VerifyProvider
+		v.BaseURL, v.APIKey, v.Model = "", "", ""
+	}
+	if c.VerifyBaseURL != "" {
+		v.BaseURL = c.VerifyBaseURL
 	}
+	if c.VerifyAPIKey != "" {
+		v.APIKey = c.VerifyAPIKey
+	}
+	if c.VerifyModel != "" {
+		v.Model = c.VerifyModel
+	}
+	v.ScriptPath = c.VerifyScriptPath
+	return &v
+}
 
-	return cfg, nil
+// Validate checks that the settings required by the selected provider are present.
+func (c *Config) Validate() error {
+	if err := c.validateProvider(); err != nil {
+		return err
+	}
+
+	switch c.ScreenshotFormat {
+	case FormatJPEG, FormatPNG:
+	default:
+		return fmt.Errorf("unknown screenshot format %q (expected %q or %q)", c.ScreenshotFormat, FormatJPEG, FormatPNG)
+	}
+	if c.ScreenshotQuality < 1 || c.ScreenshotQuality > 100 {
+		return fmt.Errorf("screenshot quality must be between 1 and 100, got %d", c.ScreenshotQuality)
+	}
+
+	switch c.Approval {
+	case ApprovalNever, ApprovalAlways, ApprovalFileWrite:
+	case ApprovalKeys:
+		// An empty pattern would match, and so prompt for, every key
+		if c.ApprovalKeyPattern == "" {
+			return fmt.Errorf("approval policy %q requires an approval key pattern", ApprovalKeys)
+		}
+		if _, err := regexp.Compile(c.ApprovalKeyPattern); err != nil {
+			return fmt.Errorf("invalid approval key pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown approval policy %q (expected %q, %q or %q)", c.Approval, ApprovalAlways, ApprovalFileWrite, ApprovalKeys)
+	}
+
+	switch c.Verify {
+	case VerifyOff:
+	case VerifyPostTask, VerifyPerStep:
+		v := c.VerifierConfig()
+		if err := v.validateProvider(); err != nil {
+			return fmt.Errorf("verifier: %w", err)
+		}
+		if v.Provider != ProviderScript && v.Model == "" {
+			return fmt.Errorf("verifier: provider %q requires a verify model", v.Provider)
+		}
+	default:
+		return fmt.Errorf("unknown verify mode %q (expected %q or %q)", c.Verify, VerifyPostTask, VerifyPerStep)
+	}
+	return nil
+}
+
+// validateProvider checks the settings of the selected provider.
+func (c *Config) validateProvider() error {
+	switch c.Provider {
+	case ProviderAnthropic:
+		if c.APIKey == "" {
+			return fmt.Errorf("ANTHROPIC_API_KEY not set (set environment variable or run 'golemming' to configure)")
+		}
+	case ProviderOpenAI:
+		// Local OpenAI-compatible servers usually don't need a key
+	case ProviderScript:
+		if c.ScriptPath == "" {
+			return fmt.Errorf("script provider requires a script path")
+		}
+	default:
+		return fmt.Errorf("unknown provider %q (expected %q, %q or %q)", c.Provider, ProviderAnthropic, ProviderOpenAI, ProviderScript)
+	}
+	return nil
 }
 
 // loadFromFile loads configuration from the config file.
@@ -130,11 +360,21 @@ func (c *Config) Save() error {
 	return nil
 }
 
+// RequestTimeout returns the LLM request timeout as a duration.
+func (c *Config) RequestTimeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
 // StabilizationDelay returns the stabilization delay as a duration.
 func (c *Config) StabilizationDelay() time.Duration {
 	return time.Duration(c.StabilizationMs) * time.Millisecond
 }
 
+// StabilizationTimeout returns the longest wait for the screen to settle.
+func (c *Config) StabilizationTimeout() time.Duration {
+	return time.Duration(c.StabilizationTimeoutMs) * time.Millisecond
+}
+
 // DefaultWait returns the default wait time as a duration.
 func (c *Config) DefaultWait() time.Duration {
 	return time.Duration(c.DefaultWaitMs) * time.Millisecond
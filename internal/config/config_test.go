@@ -0,0 +1,98 @@
+package config
+
+import "testing"
+
+func TestValidateApproval(t *testing.T) {
+	tests := []struct {
+		name     string
+		approval string
+		pattern  string
+		wantErr  bool
+	}{
+		{"never", ApprovalNever, "", false},
+		{"always", ApprovalAlways, "", false},
+		{"file writes", ApprovalFileWrite, "", false},
+		{"keys", ApprovalKeys, `^(alt\+f4|ctrl\+w)$`, false},
+		{"keys without pattern", ApprovalKeys, "", true},
+		{"keys with bad pattern", ApprovalKeys, "(", true},
+		{"unknown", "sometimes", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.APIKey = "key"
+			cfg.Approval = tt.approval
+			cfg.ApprovalKeyPattern = tt.pattern
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifierConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIKey = "agent-key"
+	cfg.Verify = VerifyPostTask
+
+	// By default the verifier uses the agent's model
+	v := cfg.VerifierConfig()
+	if v.Provider != cfg.Provider || v.Model != cfg.Model || v.APIKey != "agent-key" {
+		t.Errorf("default verifier = %s %s, want the agent's %s %s", v.Provider, v.Model, cfg.Provider, cfg.Model)
+	}
+
+	// A scripted agent does not lend its script to the verifier
+	cfg.Provider = ProviderScript
+	cfg.ScriptPath = "agent.json"
+	if v := cfg.VerifierConfig(); v.ScriptPath != "" {
+		t.Errorf("verifier replays %q, want no script", v.ScriptPath)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted a scripted verifier without a script")
+	}
+	cfg.VerifyScriptPath = "verifier.json"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v with a verifier script", err)
+	}
+
+	// Another provider starts from none of the agent's provider settings
+	cfg.VerifyProvider = ProviderOpenAI
+	cfg.VerifyBaseURL = "http://localhost:8080/v1"
+	v = cfg.VerifierConfig()
+	if v.Provider != ProviderOpenAI || v.BaseURL != cfg.VerifyBaseURL || v.APIKey != "" || v.Model != "" || v.ScriptPath != "verifier.json" {
+		t.Errorf("verifier = %+v, want openai at %s", v, cfg.VerifyBaseURL)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted a verifier provider without a model")
+	}
+	cfg.VerifyModel = "local-model"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() = %v with a verifier model", err)
+	}
+}
+
+func TestSaveKeepsZeroSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GOLEMMING_PROVIDER", "")
+
+	cfg := DefaultConfig()
+	cfg.TimeoutSeconds = 0
+	cfg.MaxRetries = 0
+	cfg.RetryBudgetSeconds = 0
+	cfg.StabilizationMs = 0
+	cfg.MaxScreenshotEdge = 0
+	cfg.MaxCorrections = 0
+	cfg.RequireAbsolutePaths = false
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got := Read()
+	if got.TimeoutSeconds != 0 || got.MaxRetries != 0 || got.RetryBudgetSeconds != 0 ||
+		got.StabilizationMs != 0 || got.MaxScreenshotEdge != 0 || got.MaxCorrections != 0 {
+		t.Errorf("zero settings read back as defaults: %+v", got)
+	}
+	if got.RequireAbsolutePaths {
+		t.Error("require_absolute_paths read back as true after saving false")
+	}
+}
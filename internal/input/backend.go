@@ -0,0 +1,34 @@
+// Package input provides mouse and keyboard automation.
+package input
+
+import "errors"
+
+// ErrUnsupported is returned by NewDefault when no native input backend is
+// available for the current platform.
+var ErrUnsupported = errors.New("no input backend available for this platform")
+
+// Backend sends mouse and keyboard input to the desktop.
+//
+// Coordinates are absolute screen coordinates. Buttons are "left", "right"
+// or "middle"; key names follow the names accepted by the system prompt
+// (e.g. "enter", "ctrl", "f4") and combos join them with "+".
+type Backend interface {
+	// Move moves the mouse cursor to the specified position.
+	Move(x, y int) error
+	// Click performs a mouse click at the current position.
+	Click(button string, double bool) error
+	// Toggle presses ("down") or releases ("up") a mouse button.
+	Toggle(button string, state string) error
+	// ScrollDir scrolls the mouse wheel "up" or "down" by amount notches.
+	ScrollDir(amount int, direction string) error
+	// KeyDown presses a key.
+	KeyDown(key string) error
+	// KeyUp releases a key.
+	KeyUp(key string) error
+	// KeyPress presses and releases a key.
+	KeyPress(key string) error
+	// KeyCombo executes a key combination (e.g. "ctrl+c", "alt+f4").
+	KeyCombo(combo string) error
+	// TypeText types a string character by character.
+	TypeText(text string) error
+}
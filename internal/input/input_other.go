@@ -0,0 +1,8 @@
+//go:build !windows && !linux
+
+package input
+
+// NewDefault returns the native input backend for the current platform.
+func NewDefault() (Backend, error) {
+	return nil, ErrUnsupported
+}
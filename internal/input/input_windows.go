@@ -1,9 +1,9 @@
 //go:build windows
 
-// Package input provides mouse and keyboard automation using Windows API.
 package input
 
 import (
+	"fmt"
 	"strings"
 	"syscall"
 	"time"
@@ -196,24 +196,57 @@ var Keycode = map[string]uint16{
 	"quote":        0xDE,
 }
 
+// SendInput is the Windows input backend built on SetCursorPos and SendInput.
+type SendInput struct{}
+
+// NewSendInput creates a Windows SendInput backend.
+func NewSendInput() *SendInput {
+	return &SendInput{}
+}
+
+// NewDefault returns the native input backend for the current platform.
+func NewDefault() (Backend, error) {
+	return NewSendInput(), nil
+}
+
+// send submits a single INPUT structure and reports whether it was accepted.
+func send(input unsafe.Pointer, size uintptr) error {
+	n, _, err := procSendInput.Call(1, uintptr(input), size)
+	if n == 0 {
+		return fmt.Errorf("SendInput failed: %w", err)
+	}
+	return nil
+}
+
 // Move moves the mouse cursor to the specified position.
-func Move(x, y int) {
-	procSetCursorPos.Call(uintptr(x), uintptr(y))
+func (s *SendInput) Move(x, y int) error {
+	ok, _, err := procSetCursorPos.Call(uintptr(x), uintptr(y))
+	if ok == 0 {
+		return fmt.Errorf("SetCursorPos failed: %w", err)
+	}
+	return nil
 }
 
 // Click performs a mouse click at the current position.
-func Click(button string, double bool) {
-	Toggle(button, "down")
-	Toggle(button, "up")
+func (s *SendInput) Click(button string, double bool) error {
+	if err := s.Toggle(button, "down"); err != nil {
+		return err
+	}
+	if err := s.Toggle(button, "up"); err != nil {
+		return err
+	}
 	if double {
 		time.Sleep(50 * time.Millisecond)
-		Toggle(button, "down")
-		Toggle(button, "up")
+		if err := s.Toggle(button, "down"); err != nil {
+			return err
+		}
+		return s.Toggle(button, "up")
 	}
+	return nil
 }
 
 // Toggle presses or releases a mouse button.
-func Toggle(button string, state string) {
+func (s *SendInput) Toggle(button string, state string) error {
 	var flags uint32
 
 	switch button {
@@ -236,7 +269,7 @@ func Toggle(button string, state string) {
 			flags = MOUSEEVENTF_MIDDLEUP
 		}
 	default:
-		return
+		return fmt.Errorf("unknown mouse button: %s", button)
 	}
 
 	input := inputUnion{
@@ -246,11 +279,11 @@ func Toggle(button string, state string) {
 		},
 	}
 
-	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	return send(unsafe.Pointer(&input), unsafe.Sizeof(input))
 }
 
 // ScrollDir scrolls the mouse wheel.
-func ScrollDir(amount int, direction string) {
+func (s *SendInput) ScrollDir(amount int, direction string) error {
 	var delta int32
 	if direction == "up" {
 		delta = int32(amount * 120)
@@ -266,7 +299,7 @@ func ScrollDir(amount int, direction string) {
 		},
 	}
 
-	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	return send(unsafe.Pointer(&input), unsafe.Sizeof(input))
 }
 
 // vkToScanCode converts a virtual key code to a hardware scan code.
@@ -295,18 +328,26 @@ func isExtendedKey(vk uint16) bool {
 	return false
 }
 
-// KeyDown presses a key.
-func KeyDown(key string) {
-	key = strings.ToLower(key)
-	vk, ok := Keycode[key]
+// lookupKey returns the virtual key code for a key name.
+func lookupKey(key string) (uint16, error) {
+	vk, ok := Keycode[strings.ToLower(key)]
 	if !ok {
-		return
+		return 0, fmt.Errorf("unknown key: %s", key)
 	}
-	KeyDownVK(vk)
+	return vk, nil
+}
+
+// KeyDown presses a key.
+func (s *SendInput) KeyDown(key string) error {
+	vk, err := lookupKey(key)
+	if err != nil {
+		return err
+	}
+	return KeyDownVK(vk)
 }
 
 // KeyDownVK presses a key using its virtual key code.
-func KeyDownVK(vk uint16) {
+func KeyDownVK(vk uint16) error {
 	scanCode := vkToScanCode(vk)
 	flags := uint32(KEYEVENTF_SCANCODE)
 	if isExtendedKey(vk) {
@@ -322,21 +363,20 @@ func KeyDownVK(vk uint16) {
 		},
 	}
 
-	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	return send(unsafe.Pointer(&input), unsafe.Sizeof(input))
 }
 
 // KeyUp releases a key.
-func KeyUp(key string) {
-	key = strings.ToLower(key)
-	vk, ok := Keycode[key]
-	if !ok {
-		return
+func (s *SendInput) KeyUp(key string) error {
+	vk, err := lookupKey(key)
+	if err != nil {
+		return err
 	}
-	KeyUpVK(vk)
+	return KeyUpVK(vk)
 }
 
 // KeyUpVK releases a key using its virtual key code.
-func KeyUpVK(vk uint16) {
+func KeyUpVK(vk uint16) error {
 	scanCode := vkToScanCode(vk)
 	flags := uint32(KEYEVENTF_SCANCODE | KEYEVENTF_KEYUP)
 	if isExtendedKey(vk) {
@@ -352,47 +392,61 @@ func KeyUpVK(vk uint16) {
 		},
 	}
 
-	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	return send(unsafe.Pointer(&input), unsafe.Sizeof(input))
 }
 
 // KeyPress presses and releases a key.
-func KeyPress(key string) {
-	KeyDown(key)
-	KeyUp(key)
+func (s *SendInput) KeyPress(key string) error {
+	if err := s.KeyDown(key); err != nil {
+		return err
+	}
+	return s.KeyUp(key)
 }
 
 // KeyCombo executes a key combination (e.g., "ctrl+c", "alt+f4").
-func KeyCombo(combo string) {
+func (s *SendInput) KeyCombo(combo string) error {
 	keys := strings.Split(strings.ToLower(combo), "+")
 
+	// Resolve every key up front so a typo doesn't leave modifiers held down
+	for _, key := range keys {
+		if _, err := lookupKey(key); err != nil {
+			return err
+		}
+	}
+
 	// Press all modifier keys
 	for i := 0; i < len(keys)-1; i++ {
-		KeyDown(keys[i])
+		s.KeyDown(keys[i])
 	}
 
 	// Press and release the final key
-	if len(keys) > 0 {
-		finalKey := keys[len(keys)-1]
-		KeyDown(finalKey)
-		KeyUp(finalKey)
+	finalKey := keys[len(keys)-1]
+	err := s.KeyDown(finalKey)
+	if err == nil {
+		err = s.KeyUp(finalKey)
 	}
 
-	// Release modifier keys in reverse order
+	// Release modifier keys in reverse order, even if the final key failed
 	for i := len(keys) - 2; i >= 0; i-- {
-		KeyUp(keys[i])
+		s.KeyUp(keys[i])
 	}
+
+	return err
 }
 
 // TypeText types a string character by character using Unicode input.
-func TypeText(text string) {
+func (s *SendInput) TypeText(text string) error {
 	for _, char := range text {
-		typeChar(char)
+		if err := typeChar(char); err != nil {
+			return err
+		}
 		time.Sleep(10 * time.Millisecond)
 	}
+	return nil
 }
 
 // typeChar types a single Unicode character.
-func typeChar(char rune) {
+func typeChar(char rune) error {
 	input := keyboardInputUnion{
 		dtype: INPUT_KEYBOARD,
 		ki: keybdInput{
@@ -402,9 +456,11 @@ func typeChar(char rune) {
 	}
 
 	// Key down
-	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	if err := send(unsafe.Pointer(&input), unsafe.Sizeof(input)); err != nil {
+		return err
+	}
 
 	// Key up
 	input.ki.dwFlags = KEYEVENTF_UNICODE | KEYEVENTF_KEYUP
-	procSendInput.Call(1, uintptr(unsafe.Pointer(&input)), unsafe.Sizeof(input))
+	return send(unsafe.Pointer(&input), unsafe.Sizeof(input))
 }
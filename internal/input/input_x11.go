@@ -0,0 +1,423 @@
+//go:build linux
+
+package input
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+	"github.com/jezek/xgb/xtest"
+)
+
+// X11 mouse buttons
+const (
+	buttonLeft       = 1
+	buttonMiddle     = 2
+	buttonRight      = 3
+	buttonScrollUp   = 4
+	buttonScrollDown = 5
+)
+
+// Keysyms maps key names to X11 keysyms. Single letters and digits are
+// resolved directly from their character and are not listed here.
+var Keysyms = map[string]xproto.Keysym{
+	"backspace":    0xff08,
+	"tab":          0xff09,
+	"enter":        0xff0d,
+	"shift":        0xffe1,
+	"ctrl":         0xffe3,
+	"alt":          0xffe9,
+	"pause":        0xff13,
+	"capslock":     0xffe5,
+	"escape":       0xff1b,
+	"esc":          0xff1b,
+	"space":        0x0020,
+	"pageup":       0xff55,
+	"pagedown":     0xff56,
+	"end":          0xff57,
+	"home":         0xff50,
+	"left":         0xff51,
+	"up":           0xff52,
+	"right":        0xff53,
+	"down":         0xff54,
+	"insert":       0xff63,
+	"delete":       0xffff,
+	"del":          0xffff,
+	"lwin":         0xffeb,
+	"rwin":         0xffec,
+	"win":          0xffeb,
+	"numpad0":      0xffb0,
+	"numpad1":      0xffb1,
+	"numpad2":      0xffb2,
+	"numpad3":      0xffb3,
+	"numpad4":      0xffb4,
+	"numpad5":      0xffb5,
+	"numpad6":      0xffb6,
+	"numpad7":      0xffb7,
+	"numpad8":      0xffb8,
+	"numpad9":      0xffb9,
+	"multiply":     0xffaa,
+	"add":          0xffab,
+	"subtract":     0xffad,
+	"decimal":      0xffae,
+	"divide":       0xffaf,
+	"f1":           0xffbe,
+	"f2":           0xffbf,
+	"f3":           0xffc0,
+	"f4":           0xffc1,
+	"f5":           0xffc2,
+	"f6":           0xffc3,
+	"f7":           0xffc4,
+	"f8":           0xffc5,
+	"f9":           0xffc6,
+	"f10":          0xffc7,
+	"f11":          0xffc8,
+	"f12":          0xffc9,
+	"numlock":      0xff7f,
+	"scrolllock":   0xff14,
+	"lshift":       0xffe1,
+	"rshift":       0xffe2,
+	"lctrl":        0xffe3,
+	"rctrl":        0xffe4,
+	"lalt":         0xffe9,
+	"ralt":         0xffea,
+	"semicolon":    0x003b,
+	"equal":        0x003d,
+	"comma":        0x002c,
+	"minus":        0x002d,
+	"period":       0x002e,
+	"slash":        0x002f,
+	"grave":        0x0060,
+	"leftbracket":  0x005b,
+	"backslash":    0x005c,
+	"rightbracket": 0x005d,
+	"quote":        0x0027,
+}
+
+// X11 is the Linux input backend built on the XTEST extension.
+type X11 struct {
+	mu   sync.Mutex
+	conn *xgb.Conn
+	root xproto.Window
+
+	// Keyboard mapping cache, refreshed after we remap the spare keycode
+	minKeycode xproto.Keycode
+	perKeycode int
+	keymap     []xproto.Keysym
+	spare      xproto.Keycode // unused keycode borrowed for unmapped characters
+}
+
+// NewX11 connects to the X server named by display (empty means $DISPLAY)
+// and returns an XTEST input backend.
+func NewX11(display string) (*X11, error) {
+	conn, err := xgb.NewConnDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %w", err)
+	}
+	if err := xtest.Init(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("XTEST extension not available: %w", err)
+	}
+
+	setup := xproto.Setup(conn)
+	x := &X11{
+		conn:       conn,
+		root:       setup.DefaultScreen(conn).Root,
+		minKeycode: setup.MinKeycode,
+	}
+
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+	reply, err := xproto.GetKeyboardMapping(conn, setup.MinKeycode, count).Reply()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read keyboard mapping: %w", err)
+	}
+	x.perKeycode = int(reply.KeysymsPerKeycode)
+	x.keymap = reply.Keysyms
+	x.spare = x.findSpareKeycode()
+
+	return x, nil
+}
+
+// NewDefault returns the native input backend for the current platform.
+func NewDefault() (Backend, error) {
+	return NewX11("")
+}
+
+// Close closes the X server connection.
+func (x *X11) Close() {
+	x.conn.Close()
+}
+
+// fake sends a single synthetic event and waits for the server to accept it.
+func (x *X11) fake(eventType, detail byte, rootX, rootY int16) error {
+	err := xtest.FakeInputChecked(x.conn, eventType, detail, 0, x.root, rootX, rootY, 0).Check()
+	if err != nil {
+		return fmt.Errorf("XTEST FakeInput failed: %w", err)
+	}
+	return nil
+}
+
+// Move moves the mouse cursor to the specified position.
+func (x *X11) Move(px, py int) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.fake(xproto.MotionNotify, 0, int16(px), int16(py))
+}
+
+// Click performs a mouse click at the current position.
+func (x *X11) Click(button string, double bool) error {
+	if err := x.Toggle(button, "down"); err != nil {
+		return err
+	}
+	if err := x.Toggle(button, "up"); err != nil {
+		return err
+	}
+	if double {
+		time.Sleep(50 * time.Millisecond)
+		if err := x.Toggle(button, "down"); err != nil {
+			return err
+		}
+		return x.Toggle(button, "up")
+	}
+	return nil
+}
+
+// Toggle presses or releases a mouse button.
+func (x *X11) Toggle(button string, state string) error {
+	var detail byte
+	switch button {
+	case "left":
+		detail = buttonLeft
+	case "right":
+		detail = buttonRight
+	case "center", "middle":
+		detail = buttonMiddle
+	default:
+		return fmt.Errorf("unknown mouse button: %s", button)
+	}
+
+	eventType := byte(xproto.ButtonRelease)
+	if state == "down" {
+		eventType = xproto.ButtonPress
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.fake(eventType, detail, 0, 0)
+}
+
+// ScrollDir scrolls the mouse wheel. X11 reports each wheel notch as a
+// press and release of button 4 (up) or 5 (down).
+func (x *X11) ScrollDir(amount int, direction string) error {
+	detail := byte(buttonScrollDown)
+	if direction == "up" {
+		detail = buttonScrollUp
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for i := 0; i < amount; i++ {
+		if err := x.fake(xproto.ButtonPress, detail, 0, 0); err != nil {
+			return err
+		}
+		if err := x.fake(xproto.ButtonRelease, detail, 0, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyNameToKeysym resolves a key name to a keysym.
+func keyNameToKeysym(key string) (xproto.Keysym, error) {
+	key = strings.ToLower(key)
+	if sym, ok := Keysyms[key]; ok {
+		return sym, nil
+	}
+	if len(key) == 1 && (key[0] >= 'a' && key[0] <= 'z' || key[0] >= '0' && key[0] <= '9') {
+		return xproto.Keysym(key[0]), nil
+	}
+	return 0, fmt.Errorf("unknown key: %s", key)
+}
+
+// runeToKeysym returns the keysym that produces r. Latin-1 characters map
+// directly; everything else uses the Unicode keysym range.
+func runeToKeysym(r rune) xproto.Keysym {
+	switch r {
+	case '\n', '\r':
+		return Keysyms["enter"]
+	case '\t':
+		return Keysyms["tab"]
+	}
+	if r >= 0x20 && r <= 0xff {
+		return xproto.Keysym(r)
+	}
+	return xproto.Keysym(0x01000000 | r)
+}
+
+// lookupKeysym finds a keycode producing sym, reporting whether shift must
+// be held to get it. Must be called with x.mu held.
+func (x *X11) lookupKeysym(sym xproto.Keysym) (xproto.Keycode, bool, bool) {
+	for col := 0; col < 2 && col < x.perKeycode; col++ {
+		for i := 0; i*x.perKeycode+col < len(x.keymap); i++ {
+			if x.keymap[i*x.perKeycode+col] == sym {
+				return x.minKeycode + xproto.Keycode(i), col == 1, true
+			}
+		}
+	}
+	return 0, false, false
+}
+
+// findSpareKeycode returns a keycode with no keysyms bound, or 0 if the
+// keymap is full.
+func (x *X11) findSpareKeycode() xproto.Keycode {
+	for i := len(x.keymap)/x.perKeycode - 1; i >= 0; i-- {
+		empty := true
+		for col := 0; col < x.perKeycode; col++ {
+			if x.keymap[i*x.perKeycode+col] != 0 {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			return x.minKeycode + xproto.Keycode(i)
+		}
+	}
+	return 0
+}
+
+// keycodeFor returns a keycode for sym, binding it to the spare keycode if
+// the current layout has no key for it. Must be called with x.mu held.
+func (x *X11) keycodeFor(sym xproto.Keysym) (xproto.Keycode, bool, error) {
+	if code, shift, ok := x.lookupKeysym(sym); ok {
+		return code, shift, nil
+	}
+	if x.spare == 0 {
+		return 0, false, fmt.Errorf("no keycode available for keysym 0x%x", sym)
+	}
+
+	syms := make([]xproto.Keysym, x.perKeycode)
+	for i := range syms {
+		syms[i] = sym
+	}
+	err := xproto.ChangeKeyboardMappingChecked(x.conn, 1, x.spare, byte(x.perKeycode), syms).Check()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to remap keycode: %w", err)
+	}
+	offset := int(x.spare-x.minKeycode) * x.perKeycode
+	copy(x.keymap[offset:offset+x.perKeycode], syms)
+
+	// Give clients a moment to process the MappingNotify before the key arrives
+	time.Sleep(20 * time.Millisecond)
+	return x.spare, false, nil
+}
+
+// pressKeysym presses or releases the key for sym, holding shift if the
+// keysym lives on the shifted level.
+func (x *X11) pressKeysym(sym xproto.Keysym, down bool) error {
+	code, shift, err := x.keycodeFor(sym)
+	if err != nil {
+		return err
+	}
+	shiftCode, _, _ := x.lookupKeysym(Keysyms["shift"])
+	if shift && shiftCode != 0 && down {
+		if err := x.fake(xproto.KeyPress, byte(shiftCode), 0, 0); err != nil {
+			return err
+		}
+	}
+
+	eventType := byte(xproto.KeyRelease)
+	if down {
+		eventType = xproto.KeyPress
+	}
+	if err := x.fake(eventType, byte(code), 0, 0); err != nil {
+		return err
+	}
+
+	if shift && shiftCode != 0 && !down {
+		return x.fake(xproto.KeyRelease, byte(shiftCode), 0, 0)
+	}
+	return nil
+}
+
+// KeyDown presses a key.
+func (x *X11) KeyDown(key string) error {
+	sym, err := keyNameToKeysym(key)
+	if err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.pressKeysym(sym, true)
+}
+
+// KeyUp releases a key.
+func (x *X11) KeyUp(key string) error {
+	sym, err := keyNameToKeysym(key)
+	if err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.pressKeysym(sym, false)
+}
+
+// KeyPress presses and releases a key.
+func (x *X11) KeyPress(key string) error {
+	if err := x.KeyDown(key); err != nil {
+		return err
+	}
+	return x.KeyUp(key)
+}
+
+// KeyCombo executes a key combination (e.g., "ctrl+c", "alt+f4").
+func (x *X11) KeyCombo(combo string) error {
+	keys := strings.Split(strings.ToLower(combo), "+")
+
+	// Resolve every key up front so a typo doesn't leave modifiers held down
+	for _, key := range keys {
+		if _, err := keyNameToKeysym(key); err != nil {
+			return err
+		}
+	}
+
+	// Press all modifier keys
+	for i := 0; i < len(keys)-1; i++ {
+		x.KeyDown(keys[i])
+	}
+
+	// Press and release the final key
+	finalKey := keys[len(keys)-1]
+	err := x.KeyDown(finalKey)
+	if err == nil {
+		err = x.KeyUp(finalKey)
+	}
+
+	// Release modifier keys in reverse order, even if the final key failed
+	for i := len(keys) - 2; i >= 0; i-- {
+		x.KeyUp(keys[i])
+	}
+
+	return err
+}
+
+// TypeText types a string character by character.
+func (x *X11) TypeText(text string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, char := range text {
+		sym := runeToKeysym(char)
+		if err := x.pressKeysym(sym, true); err != nil {
+			return err
+		}
+		if err := x.pressKeysym(sym, false); err != nil {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+//go:build linux
+
+package input
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jezek/xgb"
+	"github.com/jezek/xgb/xproto"
+)
+
+// These tests run against a real X server and are skipped without one.
+// make test-xvfb runs them under Xvfb.
+
+// newX11 connects the backend to $DISPLAY, skipping the test without one.
+func newX11(t *testing.T) *X11 {
+	t.Helper()
+	if os.Getenv("DISPLAY") == "" {
+		t.Skip("DISPLAY is not set; run under Xvfb with make test-xvfb")
+	}
+	x, err := NewX11("")
+	if err != nil {
+		t.Fatalf("NewX11: %v", err)
+	}
+	t.Cleanup(x.Close)
+	return x
+}
+
+// testWindow is a focused window covering the screen, on a connection of
+// its own, that receives the events the backend fakes.
+type testWindow struct {
+	conn   *xgb.Conn
+	events chan xgb.Event
+}
+
+func newTestWindow(t *testing.T) *testWindow {
+	t.Helper()
+	conn, err := xgb.NewConn()
+	if err != nil {
+		t.Fatalf("failed to connect to X server: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	win, err := xproto.NewWindowId(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := uint32(xproto.EventMaskKeyPress | xproto.EventMaskKeyRelease |
+		xproto.EventMaskButtonPress | xproto.EventMaskButtonRelease | xproto.EventMaskStructureNotify)
+	err = xproto.CreateWindowChecked(conn, screen.RootDepth, win, screen.Root,
+		0, 0, screen.WidthInPixels, screen.HeightInPixels, 0,
+		xproto.WindowClassInputOutput, screen.RootVisual,
+		xproto.CwBackPixel|xproto.CwOverrideRedirect|xproto.CwEventMask,
+		[]uint32{screen.WhitePixel, 1, mask}).Check()
+	if err != nil {
+		t.Fatalf("CreateWindow: %v", err)
+	}
+
+	w := &testWindow{conn: conn, events: make(chan xgb.Event, 256)}
+	go func() {
+		for {
+			ev, err := conn.WaitForEvent()
+			if ev == nil && err == nil {
+				return // connection closed
+			}
+			if ev != nil {
+				w.events <- ev
+			}
+		}
+	}()
+
+	if err := xproto.MapWindowChecked(conn, win).Check(); err != nil {
+		t.Fatalf("MapWindow: %v", err)
+	}
+	w.next(t, func(ev xgb.Event) bool {
+		_, ok := ev.(xproto.MapNotifyEvent)
+		return ok
+	})
+	if err := xproto.SetInputFocusChecked(conn, xproto.InputFocusPointerRoot, win, xproto.TimeCurrentTime).Check(); err != nil {
+		t.Fatalf("SetInputFocus: %v", err)
+	}
+	return w
+}
+
+// next returns the next event accepted by want, skipping others.
+func (w *testWindow) next(t *testing.T, want func(xgb.Event) bool) xgb.Event {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-w.events:
+			if want(ev) {
+				return ev
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for an X event")
+			return nil
+		}
+	}
+}
+
+// button is a button event received by the window.
+type button struct {
+	press  bool
+	detail xproto.Button
+	x, y   int16
+}
+
+// nextButton returns the next button press or release.
+func (w *testWindow) nextButton(t *testing.T) button {
+	t.Helper()
+	switch ev := w.next(t, func(ev xgb.Event) bool {
+		switch ev.(type) {
+		case xproto.ButtonPressEvent, xproto.ButtonReleaseEvent:
+			return true
+		}
+		return false
+	}).(type) {
+	case xproto.ButtonPressEvent:
+		return button{true, ev.Detail, ev.EventX, ev.EventY}
+	case xproto.ButtonReleaseEvent:
+		return button{false, ev.Detail, ev.EventX, ev.EventY}
+	}
+	return button{}
+}
+
+// nextKeyPress returns the next key press, skipping releases.
+func (w *testWindow) nextKeyPress(t *testing.T) xproto.KeyPressEvent {
+	t.Helper()
+	return w.next(t, func(ev xgb.Event) bool {
+		_, ok := ev.(xproto.KeyPressEvent)
+		return ok
+	}).(xproto.KeyPressEvent)
+}
+
+// keysym returns the keysym the current keyboard mapping gives a key
+// event, taking shift into account.
+func (w *testWindow) keysym(t *testing.T, code xproto.Keycode, state uint16) xproto.Keysym {
+	t.Helper()
+	setup := xproto.Setup(w.conn)
+	reply, err := xproto.GetKeyboardMapping(w.conn, setup.MinKeycode, byte(setup.MaxKeycode-setup.MinKeycode+1)).Reply()
+	if err != nil {
+		t.Fatalf("GetKeyboardMapping: %v", err)
+	}
+	per := int(reply.KeysymsPerKeycode)
+	col := 0
+	if state&xproto.ModMaskShift != 0 && per > 1 {
+		col = 1
+	}
+	return reply.Keysyms[int(code-setup.MinKeycode)*per+col]
+}
+
+// assertReleased fails if any key or mouse button is still held down.
+func (w *testWindow) assertReleased(t *testing.T) {
+	t.Helper()
+	keys, err := xproto.QueryKeymap(w.conn).Reply()
+	if err != nil {
+		t.Fatalf("QueryKeymap: %v", err)
+	}
+	for i, bits := range keys.Keys {
+		if bits != 0 {
+			t.Errorf("keys still held down: byte %d of the keymap is %08b", i, bits)
+		}
+	}
+	pointer, err := xproto.QueryPointer(w.conn, xproto.Setup(w.conn).DefaultScreen(w.conn).Root).Reply()
+	if err != nil {
+		t.Fatalf("QueryPointer: %v", err)
+	}
+	held := uint16(xproto.KeyButMaskButton1 | xproto.KeyButMaskButton2 | xproto.KeyButMaskButton3)
+	if pointer.Mask&held != 0 {
+		t.Errorf("mouse buttons still held down: mask %#x", pointer.Mask)
+	}
+}
+
+func TestX11Move(t *testing.T) {
+	x := newX11(t)
+	if err := x.Move(37, 41); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	reply, err := xproto.QueryPointer(x.conn, x.root).Reply()
+	if err != nil {
+		t.Fatalf("QueryPointer: %v", err)
+	}
+	if reply.RootX != 37 || reply.RootY != 41 {
+		t.Errorf("pointer at (%d, %d), want (37, 41)", reply.RootX, reply.RootY)
+	}
+}
+
+func TestX11Click(t *testing.T) {
+	x := newX11(t)
+	w := newTestWindow(t)
+
+	if err := x.Move(100, 60); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if err := x.Click("right", false); err != nil {
+		t.Fatalf("Click right: %v", err)
+	}
+	if err := x.Click("left", true); err != nil {
+		t.Fatalf("Click left double: %v", err)
+	}
+
+	want := []button{
+		{true, buttonRight, 100, 60}, {false, buttonRight, 100, 60},
+		{true, buttonLeft, 100, 60}, {false, buttonLeft, 100, 60},
+		{true, buttonLeft, 100, 60}, {false, buttonLeft, 100, 60},
+	}
+	for i, wantEv := range want {
+		if got := w.nextButton(t); got != wantEv {
+			t.Errorf("button event %d = %+v, want %+v", i+1, got, wantEv)
+		}
+	}
+	w.assertReleased(t)
+
+	if err := x.Click("thumb", false); err == nil {
+		t.Error("Click with an unknown button succeeded")
+	}
+}
+
+func TestX11Keys(t *testing.T) {
+	x := newX11(t)
+	w := newTestWindow(t)
+
+	if err := x.KeyPress("f5"); err != nil {
+		t.Fatalf("KeyPress: %v", err)
+	}
+	press := w.nextKeyPress(t)
+	if sym := w.keysym(t, press.Detail, press.State); sym != Keysyms["f5"] {
+		t.Errorf("KeyPress f5 sent keysym %#x, want %#x", sym, Keysyms["f5"])
+	}
+
+	// The final key of a combo arrives with its modifiers held
+	if err := x.KeyCombo("ctrl+shift+a"); err != nil {
+		t.Fatalf("KeyCombo: %v", err)
+	}
+	for {
+		press = w.nextKeyPress(t)
+		if sym := w.keysym(t, press.Detail, 0); sym == 'a' {
+			break
+		}
+	}
+	if want := uint16(xproto.ModMaskControl | xproto.ModMaskShift); press.State&want != want {
+		t.Errorf("a pressed with state %#x, want ctrl and shift held", press.State)
+	}
+	w.assertReleased(t)
+
+	// A typo fails before any modifier goes down
+	if err := x.KeyCombo("ctrl+nosuchkey"); err == nil {
+		t.Error("KeyCombo with an unknown key succeeded")
+	}
+	w.assertReleased(t)
+}
+
+func TestX11TypeText(t *testing.T) {
+	x := newX11(t)
+	w := newTestWindow(t)
+
+	// Shifted characters come from the shifted level of their key
+	if err := x.TypeText("aB1"); err != nil {
+		t.Fatalf("TypeText: %v", err)
+	}
+	var typed []xproto.Keysym
+	for len(typed) < 3 {
+		press := w.nextKeyPress(t)
+		if sym := w.keysym(t, press.Detail, press.State); sym != Keysyms["shift"] {
+			typed = append(typed, sym)
+		}
+	}
+	if want := []xproto.Keysym{'a', 'B', '1'}; !slices.Equal(typed, want) {
+		t.Errorf("typed keysyms %#x, want %#x", typed, want)
+	}
+	w.assertReleased(t)
+
+	// Characters missing from the layout are bound to the spare keycode
+	if x.spare == 0 {
+		t.Skip("the keymap has no spare keycode")
+	}
+	for _, r := range []rune{'€', 'ж'} {
+		if err := x.TypeText(string(r)); err != nil {
+			t.Fatalf("TypeText(%q): %v", r, err)
+		}
+		press := w.nextKeyPress(t)
+		if press.Detail != x.spare {
+			t.Errorf("%q sent on keycode %d, want the spare keycode %d", r, press.Detail, x.spare)
+		}
+		if sym, want := w.keysym(t, press.Detail, press.State), runeToKeysym(r); sym != want {
+			t.Errorf("%q sent as keysym %#x, want %#x", r, sym, want)
+		}
+	}
+	w.assertReleased(t)
+}
@@ -0,0 +1,99 @@
+package input
+
+import "sync"
+
+// Event is a single input call captured by a Recorder.
+type Event struct {
+	Kind      string // move, click, toggle, scroll, key_down, key_up, key_press, key_combo, type
+	X, Y      int
+	Button    string
+	Double    bool
+	State     string
+	Amount    int
+	Direction string
+	Key       string
+	Text      string
+}
+
+// Recorder is an in-memory Backend that records every call instead of
+// sending it to the desktop. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+
+	// Err, if set, is returned from every call after it has been recorded.
+	Err error
+}
+
+// NewRecorder creates an empty recording backend.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Events returns a copy of the recorded events in call order.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Reset discards all recorded events.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+func (r *Recorder) record(e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return r.Err
+}
+
+// Move records a cursor move.
+func (r *Recorder) Move(x, y int) error {
+	return r.record(Event{Kind: "move", X: x, Y: y})
+}
+
+// Click records a mouse click.
+func (r *Recorder) Click(button string, double bool) error {
+	return r.record(Event{Kind: "click", Button: button, Double: double})
+}
+
+// Toggle records a mouse button press or release.
+func (r *Recorder) Toggle(button string, state string) error {
+	return r.record(Event{Kind: "toggle", Button: button, State: state})
+}
+
+// ScrollDir records a mouse wheel scroll.
+func (r *Recorder) ScrollDir(amount int, direction string) error {
+	return r.record(Event{Kind: "scroll", Amount: amount, Direction: direction})
+}
+
+// KeyDown records a key press.
+func (r *Recorder) KeyDown(key string) error {
+	return r.record(Event{Kind: "key_down", Key: key})
+}
+
+// KeyUp records a key release.
+func (r *Recorder) KeyUp(key string) error {
+	return r.record(Event{Kind: "key_up", Key: key})
+}
+
+// KeyPress records a key press and release.
+func (r *Recorder) KeyPress(key string) error {
+	return r.record(Event{Kind: "key_press", Key: key})
+}
+
+// KeyCombo records a key combination.
+func (r *Recorder) KeyCombo(combo string) error {
+	return r.record(Event{Kind: "key_combo", Key: combo})
+}
+
+// TypeText records typed text.
+func (r *Recorder) TypeText(text string) error {
+	return r.record(Event{Kind: "type", Text: text})
+}
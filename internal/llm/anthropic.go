@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// Anthropic is the Provider for the Anthropic Messages API.
+type Anthropic struct {
+	client *anthropic.Client
+	model  string
+}
+
+// NewAnthropic creates an Anthropic provider from the API key, base URL,
+// timeout, retry and proxy settings in cfg.
+func NewAnthropic(cfg *config.Config) (*Anthropic, error) {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []option.RequestOption{
+		option.WithAPIKey(cfg.APIKey),
+		option.WithHTTPClient(httpClient),
+		option.WithMaxRetries(0), // Client retries with its own policy
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+	}
+	if cfg.TimeoutSeconds > 0 {
+		opts = append(opts, option.WithRequestTimeout(cfg.RequestTimeout()))
+	}
+
+	client := anthropic.NewClient(opts...)
+	return &Anthropic{
+		client: &client,
+		model:  cfg.Model,
+	}, nil
+}
+
+// Complete sends the request to the Messages API, forcing a single tool call.
+func (p *Anthropic) Complete(ctx context.Context, req *Request) (Message, error) {
+	message, err := p.client.Messages.New(ctx, anthropic.MessageNewParams{
+		Model:     anthropic.Model(p.model),
+		MaxTokens: int64(req.MaxTokens),
+		System: []anthropic.TextBlockParam{
+			{Text: req.System},
+		},
+		Messages: toAnthropicMessages(req.Messages),
+		Tools:    toAnthropicTools(req.Tools),
+		ToolChoice: anthropic.ToolChoiceUnionParam{
+			OfToolChoiceAny: &anthropic.ToolChoiceAnyParam{
+				DisableParallelToolUse: anthropic.Bool(true),
+			},
+		},
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to call Anthropic API: %w", err)
+		var apiErr *anthropic.Error
+		if errors.As(err, &apiErr) {
+			var header http.Header
+			if apiErr.Response != nil {
+				header = apiErr.Response.Header
+			}
+			return Message{}, &APIError{StatusCode: apiErr.StatusCode, RetryAfter: parseRetryAfter(header), Err: err}
+		}
+		return Message{}, err
+	}
+	return fromAnthropicMessage(message), nil
+}
+
+// toAnthropicTools declares every protocol action as an Anthropic tool.
+func toAnthropicTools(specs []protocol.Tool) []anthropic.ToolUnionParam {
+	tools := make([]anthropic.ToolUnionParam, 0, len(specs))
+	for _, t := range specs {
+		schema := t.InputSchema()
+		inputSchema := anthropic.ToolInputSchemaParam{Properties: schema["properties"]}
+		inputSchema.WithExtraFields(map[string]any{"required": schema["required"]})
+		tools = append(tools, anthropic.ToolUnionParam{
+			OfTool: &anthropic.ToolParam{
+				Name:        string(t.Type),
+				Description: anthropic.String(t.Description),
+				InputSchema: inputSchema,
+			},
+		})
+	}
+	return tools
+}
+
+// toAnthropicMessages converts a transcript to Anthropic request messages.
+func toAnthropicMessages(messages []Message) []anthropic.MessageParam {
+	params := make([]anthropic.MessageParam, 0, len(messages))
+	for _, msg := range messages {
+		blocks := make([]anthropic.ContentBlockParamUnion, 0, len(msg.Content))
+		for _, block := range msg.Content {
+			switch block.Type {
+			case BlockText:
+				blocks = append(blocks, anthropic.NewTextBlock(block.Text))
+			case BlockImage:
+				blocks = append(blocks, anthropic.NewImageBlockBase64(block.MediaType, block.Image))
+			case BlockToolUse:
+				blocks = append(blocks, anthropic.ContentBlockParamUnion{
+					OfRequestToolUseBlock: &anthropic.ToolUseBlockParam{
+						ID:    block.ToolID,
+						Name:  block.ToolName,
+						Input: block.ToolInput,
+					},
+				})
+			case BlockToolResult:
+				blocks = append(blocks, anthropic.NewToolResultBlock(block.ToolID, block.Text, block.IsError))
+			}
+		}
+		if msg.Role == RoleAssistant {
+			params = append(params, anthropic.NewAssistantMessage(blocks...))
+		} else {
+			params = append(params, anthropic.NewUserMessage(blocks...))
+		}
+	}
+	return params
+}
+
+// fromAnthropicMessage converts an Anthropic response to a transcript message.
+func fromAnthropicMessage(message *anthropic.Message) Message {
+	msg := Message{
+		Role: RoleAssistant,
+		Usage: Usage{
+			InputTokens:  int(message.Usage.InputTokens),
+			OutputTokens: int(message.Usage.OutputTokens),
+		},
+	}
+	for _, block := range message.Content {
+		switch block.Type {
+		case "text":
+			msg.Content = append(msg.Content, TextBlock(block.Text))
+		case "tool_use":
+			msg.Content = append(msg.Content, Block{
+				Type:      BlockToolUse,
+				ToolID:    block.ID,
+				ToolName:  block.Name,
+				ToolInput: block.Input,
+			})
+		}
+	}
+	return msg
+}
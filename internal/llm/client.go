@@ -3,65 +3,233 @@ package llm
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
-	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/thesimpledev/golemming/internal/config"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
-// Client wraps the Anthropic API client.
+// Client asks a model provider for the next action, keeping the
+// conversation state and correcting invalid tool calls.
 type Client struct {
-	client *anthropic.Client
-	model  string
+	provider       Provider
+	retry          RetryPolicy
+	onRetry        func(RetryEvent)
+	maxCorrections int
+	onInvalid      func(*InvalidResponseError)
+	onExchange     func(Exchange)
+
+	// conversation is the running transcript in conversation mode, or nil
+	// to send a fresh single message per step.
+	conversation *Conversation
+}
+
+// NewClient creates a new LLM client using the provider, credentials and
+// conversation settings in cfg.
+func NewClient(cfg *config.Config) (*Client, error) {
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithProvider(provider, cfg), nil
 }
 
-// NewClient creates a new LLM client.
-func NewClient(apiKey, model string) *Client {
-	client := anthropic.NewClient()
-	return &Client{
-		client: &client,
-		model:  model,
+// NewClientWithProvider creates a new LLM client backed by provider, taking
+// only the conversation settings from cfg.
+func NewClientWithProvider(provider Provider, cfg *config.Config) *Client {
+	c := &Client{
+		provider:       provider,
+		retry:          RetryPolicyFromConfig(cfg),
+		maxCorrections: max(cfg.MaxCorrections, 0),
+	}
+	if cfg.ConversationMode {
+		c.conversation = NewConversation(cfg.ImageWindow)
 	}
+	return c
+}
+
+// OnRetry sets a callback for when a failed API call is about to be retried.
+func (c *Client) OnRetry(fn func(RetryEvent)) {
+	c.onRetry = fn
+}
+
+// OnInvalidResponse sets a callback for each model response that is
+// rejected because it could not be parsed or failed validation.
+func (c *Client) OnInvalidResponse(fn func(*InvalidResponseError)) {
+	c.onInvalid = fn
+}
+
+// Exchange is one request to the provider and its outcome, for recording.
+type Exchange struct {
+	System   string
+	Messages []Message // messages added to the transcript since the last exchange
+	Reply    Message
+	Err      error
+	Duration time.Duration // including retries
 }
 
-// GetAction sends a screenshot and context to the LLM and returns the next action.
-func (c *Client) GetAction(ctx context.Context, goal string, screenshotBase64 string, history []HistoryEntry) (*protocol.Action, error) {
-	userPrompt := BuildUserPrompt(goal, history)
-
-	// Build the message with image
-	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.Model(c.model),
-		MaxTokens: 1024,
-		System: []anthropic.TextBlockParam{
-			{Text: SystemPrompt},
-		},
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(
-				anthropic.NewTextBlock(userPrompt),
-				anthropic.NewImageBlockBase64("image/jpeg", screenshotBase64),
-			),
-		},
+// OnExchange sets a callback for every request sent to the provider,
+// including correction turns.
+func (c *Client) OnExchange(fn func(Exchange)) {
+	c.onExchange = fn
+}
+
+// Screenshot is the current screen image sent with a request.
+type Screenshot struct {
+	Data        string // base64 encoded image
+	MediaType   string
+	Description string // what the image shows, e.g. which display
+
+	// Unchanged is set when the screen looks the same as in the previous
+	// screenshot, which then need not be sent again.
+	Unchanged bool
+}
+
+// GetAction sends a screenshot and context to the LLM and returns the next
+// action. notes is guidance from the operator for this step, if any.
+func (c *Client) GetAction(ctx context.Context, goal string, shot Screenshot, history []HistoryEntry, notes []string) (*protocol.Action, error) {
+	// In single-message mode every step starts a throwaway conversation
+	conv := c.conversation
+	if conv == nil {
+		conv = NewConversation(1)
+	}
+	start := conv.mark()
+	conv.Observe(goal, shot, history, notes)
+
+	var action *protocol.Action
+	err := c.complete(ctx, conv, SystemPrompt, protocol.Tools, func(reply Message) error {
+		var err error
+		action, err = parseReply(reply)
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Anthropic API: %w", err)
+		conv.undo(start)
+		return nil, err
+	}
+	return action, nil
+}
+
+// CancelAction tells the model, with the next step, that the action it
+// last chose was not executed, e.g. because the step was interrupted
+// before it ran. It does nothing in single-message mode, where each step
+// starts afresh.
+func (c *Client) CancelAction(reason string) {
+	if c.conversation != nil {
+		c.conversation.Cancel(reason)
+	}
+}
+
+// complete sends the conversation to the provider until parse accepts the
+// reply, handing parse errors back to the model for correction.
+func (c *Client) complete(ctx context.Context, conv *Conversation, system string, tools []protocol.Tool, parse func(Message) error) error {
+	for attempt := 0; ; attempt++ {
+		req := &Request{
+			System:    system,
+			Messages:  conv.Messages(),
+			Tools:     tools,
+			MaxTokens: 1024,
+		}
+		var reply Message
+		start := time.Now()
+		err := c.retry.do(ctx, c.onRetry, func() error {
+			var err error
+			reply, err = c.provider.Complete(ctx, req)
+			return err
+		})
+		if c.onExchange != nil {
+			c.onExchange(Exchange{
+				System:   req.System,
+				Messages: req.Messages[min(conv.exchanged, len(req.Messages)):],
+				Reply:    reply,
+				Err:      err,
+				Duration: time.Since(start),
+			})
+			conv.exchanged = len(req.Messages)
+			if err == nil {
+				conv.exchanged++ // the reply joins the transcript
+			}
+		}
+		if err != nil {
+			return err
+		}
+		conv.Reply(reply)
+
+		err = parse(reply)
+		if err == nil {
+			return nil
+		}
+
+		invalid := &InvalidResponseError{
+			Response: RawResponse(reply),
+			Attempt:  attempt + 1,
+			Err:      err,
+		}
+		if c.onInvalid != nil {
+			c.onInvalid(invalid)
+		}
+		if attempt >= c.maxCorrections {
+			return fmt.Errorf("no valid response after %d corrections: %w", attempt, invalid)
+		}
+
+		// Hand the error back so the model can correct itself
+		conv.Reject(err.Error())
 	}
+}
+
+// InvalidResponseError is a model response that could not be turned into
+// a valid action.
+type InvalidResponseError struct {
+	Response string // the rejected response as returned by the model
+	Attempt  int    // 1 for the first response of a step
+	Err      error  // the parse or validation error
+}
+
+func (e *InvalidResponseError) Error() string {
+	return "invalid response from LLM: " + e.Err.Error()
+}
+
+func (e *InvalidResponseError) Unwrap() error {
+	return e.Err
+}
+
+// RawResponse renders a response as text: tool calls as their name and
+// JSON input, text blocks verbatim.
+func RawResponse(reply Message) string {
+	var parts []string
+	for _, block := range reply.Content {
+		switch block.Type {
+		case BlockToolUse:
+			parts = append(parts, block.ToolName+" "+string(block.ToolInput))
+		case BlockText:
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
 
-	// Extract text response
-	if len(message.Content) == 0 {
+// parseReply extracts the action from a response.
+func parseReply(reply Message) (*protocol.Action, error) {
+	if len(reply.Content) == 0 {
 		return nil, fmt.Errorf("empty response from LLM")
 	}
 
 	var responseText string
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			responseText = block.Text
-			break
+	for _, block := range reply.Content {
+		switch block.Type {
+		case BlockToolUse:
+			return ParseToolCall(block.ToolName, block.ToolInput)
+		case BlockText:
+			if responseText == "" {
+				responseText = block.Text
+			}
 		}
 	}
 
 	if responseText == "" {
-		return nil, fmt.Errorf("no text response from LLM")
+		return nil, fmt.Errorf("no tool call or text response from LLM")
 	}
 
-	// Parse the action
+	// Fall back to a JSON action in plain text
 	return ParseAction(responseText)
 }
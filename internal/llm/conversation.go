@@ -0,0 +1,183 @@
+package llm
+
+// screenshotPlaceholder replaces screenshots that have fallen out of the
+// image window.
+const screenshotPlaceholder = "[earlier screenshot omitted]"
+
+// unchangedScreenshot stands in for a screenshot identical to the last one.
+const unchangedScreenshot = "## Current Screenshot\nThe screen shows no visible change since the previous screenshot, so it is not repeated. Decide the next action.\n"
+
+// Conversation is the multi-turn transcript of an agent run. Unlike the
+// single-message mode, the model sees its own earlier tool calls, their
+// results and the most recent screenshots, so it can compare before and
+// after states.
+type Conversation struct {
+	messages    []Message
+	imageWindow int
+
+	seen      int    // history entries already reported to the model
+	pendingID string // tool_use awaiting a tool_result
+	cancelled string // why the pending tool call was not executed, if so
+	exchanged int    // messages already passed to an exchange callback
+}
+
+// NewConversation creates an empty conversation that keeps at most
+// imageWindow screenshots; older ones are replaced by a placeholder.
+func NewConversation(imageWindow int) *Conversation {
+	if imageWindow < 1 {
+		imageWindow = 1
+	}
+	return &Conversation{imageWindow: imageWindow}
+}
+
+// Observe appends a user turn for the current step. The first turn carries
+// the goal; later turns answer the previous tool call with the result
+// recorded in history and report any other entries added since. Operator
+// notes are passed on in the turn.
+func (c *Conversation) Observe(goal string, shot Screenshot, history []HistoryEntry, notes []string) {
+	if c.seen > len(history) {
+		c.seen = len(history)
+	}
+	newEntries := history[c.seen:]
+	c.seen = len(history)
+
+	if len(c.messages) == 0 {
+		c.messages = append(c.messages, Message{
+			Role: RoleUser,
+			Content: []Block{
+				TextBlock(BuildUserPrompt(goal, history, notes, shot.Description)),
+				ImageBlock(shot.MediaType, shot.Data),
+			},
+		})
+		return
+	}
+
+	var content []Block
+	if c.pendingID != "" {
+		result, isError := "OK", false
+		if c.cancelled != "" {
+			result, isError = c.cancelled, true
+		} else if len(newEntries) > 0 {
+			result = formatToolResult(newEntries[0])
+			isError = newEntries[0].Error != ""
+			newEntries = newEntries[1:]
+		}
+		content = append(content, ToolResultBlock(c.pendingID, result, isError))
+		c.pendingID = ""
+		c.cancelled = ""
+	}
+
+	text := ""
+	if len(newEntries) > 0 {
+		text += "## Since Your Last Action\n"
+		for i, entry := range newEntries {
+			text += formatHistoryEntry(c.seen-len(newEntries)+i+1, entry)
+		}
+		text += "\n"
+	}
+	text += formatNotes(notes)
+	if shot.Unchanged {
+		// The previous screenshot is still the newest image in the transcript
+		text += unchangedScreenshot
+		content = append(content, TextBlock(text))
+	} else {
+		text += currentScreenshot(shot.Description)
+		content = append(content, TextBlock(text), ImageBlock(shot.MediaType, shot.Data))
+	}
+	c.messages = append(c.messages, Message{Role: RoleUser, Content: content})
+}
+
+// conversationMark is a point in a conversation that undo returns to.
+type conversationMark struct {
+	messages  int
+	seen      int
+	pendingID string
+	cancelled string
+}
+
+// mark returns the current point in the conversation, for undo.
+func (c *Conversation) mark() conversationMark {
+	return conversationMark{messages: len(c.messages), seen: c.seen, pendingID: c.pendingID, cancelled: c.cancelled}
+}
+
+// undo drops the turns added since m was taken, so that a step abandoned
+// before the model answered, e.g. by a pause, is asked again from scratch
+// rather than repeating its observations and notes in a second turn.
+func (c *Conversation) undo(m conversationMark) {
+	c.messages = c.messages[:m.messages]
+	c.seen = m.seen
+	c.pendingID = m.pendingID
+	c.cancelled = m.cancelled
+	c.exchanged = min(c.exchanged, m.messages)
+}
+
+// Ask appends a user turn with text followed by a screenshot.
+func (c *Conversation) Ask(text string, shot Screenshot) {
+	c.messages = append(c.messages, Message{
+		Role:    RoleUser,
+		Content: []Block{TextBlock(text), ImageBlock(shot.MediaType, shot.Data)},
+	})
+}
+
+// Reply records the model's response. A tool call in the reply is left
+// pending until the next Observe or Reject answers it.
+func (c *Conversation) Reply(msg Message) {
+	c.messages = append(c.messages, msg)
+	c.pendingID = ""
+	c.cancelled = ""
+	for _, block := range msg.Content {
+		if block.Type == BlockToolUse {
+			c.pendingID = block.ToolID
+			break
+		}
+	}
+}
+
+// Cancel has the next Observe answer the pending tool call with reason as
+// an error, for a call that was never executed, instead of with the result
+// of the next history entry.
+func (c *Conversation) Cancel(reason string) {
+	if c.pendingID != "" {
+		c.cancelled = reason
+	}
+}
+
+// Reject tells the model its last response was invalid so it can correct
+// itself within the same step. A pending tool call is answered with an
+// error result; a plain text response gets a text correction.
+func (c *Conversation) Reject(reason string) {
+	block := ToolResultBlock(c.pendingID, reason, true)
+	if c.pendingID == "" {
+		block = TextBlock("Your last response was not a valid action: " + reason +
+			"\nRespond by calling exactly one tool.")
+	}
+	c.messages = append(c.messages, Message{
+		Role:    RoleUser,
+		Content: []Block{block},
+	})
+	c.pendingID = ""
+	c.cancelled = ""
+}
+
+// Messages returns the transcript to send, keeping only the newest
+// screenshots within the image window.
+func (c *Conversation) Messages() []Message {
+	out := make([]Message, len(c.messages))
+	images := 0
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		msg := c.messages[i]
+		content := make([]Block, len(msg.Content))
+		for j := len(msg.Content) - 1; j >= 0; j-- {
+			block := msg.Content[j]
+			if block.Type == BlockImage {
+				images++
+				if images > c.imageWindow {
+					block = TextBlock(screenshotPlaceholder)
+				}
+			}
+			content[j] = block
+		}
+		out[i] = Message{Role: msg.Role, Content: content}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// interruptedProvider fails its first call as if the step had been
+// interrupted, then answers with a key press, keeping every request.
+type interruptedProvider struct {
+	requests []*Request
+}
+
+func (p *interruptedProvider) Complete(ctx context.Context, req *Request) (Message, error) {
+	p.requests = append(p.requests, req)
+	if len(p.requests) == 1 {
+		return Message{}, context.Canceled
+	}
+	return Message{
+		Role: RoleAssistant,
+		Content: []Block{{
+			Type:      BlockToolUse,
+			ToolID:    "call",
+			ToolName:  string(protocol.ActionKey),
+			ToolInput: []byte(`{"key": "enter"}`),
+		}},
+	}, nil
+}
+
+func TestConversationInterruptedStep(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ConversationMode = true
+	cfg.MaxRetries = 0
+	provider := &interruptedProvider{}
+	client := NewClientWithProvider(provider, cfg)
+
+	notes := []string{"use the File menu"}
+	if _, err := client.GetAction(context.Background(), "save", testShot, nil, notes); err == nil {
+		t.Fatal("interrupted step succeeded")
+	}
+	if _, err := client.GetAction(context.Background(), "save", testShot, nil, notes); err != nil {
+		t.Fatalf("GetAction: %v", err)
+	}
+
+	// The retried step replaces the abandoned turn instead of following it
+	messages := provider.requests[1].Messages
+	if len(messages) != 1 || messages[0].Role != RoleUser {
+		t.Fatalf("retry sent %d messages, want the single user turn", len(messages))
+	}
+	if n := strings.Count(messages[0].Content[0].Text, "use the File menu"); n != 1 {
+		t.Errorf("note sent %d times, want once", n)
+	}
+}
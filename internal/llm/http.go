@@ -0,0 +1,23 @@
+package llm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/thesimpledev/golemming/internal/config"
+)
+
+// newHTTPClient builds the HTTP client shared by the API providers,
+// routing through the configured proxy if one is set.
+func newHTTPClient(cfg *config.Config) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", cfg.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport}, nil
+}
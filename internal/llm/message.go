@@ -0,0 +1,70 @@
+package llm
+
+import "encoding/json"
+
+// Role identifies who authored a message.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// BlockType identifies the kind of content in a Block.
+type BlockType string
+
+const (
+	BlockText       BlockType = "text"
+	BlockImage      BlockType = "image"
+	BlockToolUse    BlockType = "tool_use"
+	BlockToolResult BlockType = "tool_result"
+)
+
+// Message is a single turn of a conversation with the model.
+type Message struct {
+	Role    Role
+	Content []Block
+
+	// Usage is the token usage reported for a model reply.
+	Usage Usage
+}
+
+// Usage counts the tokens a request consumed.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// Block is one piece of message content. Only the fields relevant to Type
+// are set.
+type Block struct {
+	Type BlockType
+
+	// Text holds the text of a text block or the content of a tool result.
+	Text string
+
+	// Image data for image blocks, base64 encoded.
+	Image     string
+	MediaType string
+
+	// Tool call fields. ToolID links a tool_result to its tool_use.
+	ToolID    string
+	ToolName  string
+	ToolInput json.RawMessage
+	IsError   bool
+}
+
+// TextBlock creates a text block.
+func TextBlock(text string) Block {
+	return Block{Type: BlockText, Text: text}
+}
+
+// ImageBlock creates a base64 image block.
+func ImageBlock(mediaType, data string) Block {
+	return Block{Type: BlockImage, MediaType: mediaType, Image: data}
+}
+
+// ToolResultBlock creates a tool_result block answering the tool_use with id.
+func ToolResultBlock(id, content string, isError bool) Block {
+	return Block{Type: BlockToolResult, ToolID: id, Text: content, IsError: isError}
+}
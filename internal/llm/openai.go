@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// DefaultOpenAIBaseURL is used when no base URL is configured.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI is the Provider for OpenAI-compatible chat completion APIs,
+// including local servers such as vLLM and llama.cpp.
+type OpenAI struct {
+	baseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+// NewOpenAI creates an OpenAI-compatible provider from cfg. An empty base
+// URL uses the OpenAI API and an empty API key sends no Authorization header.
+func NewOpenAI(cfg *config.Config) (*OpenAI, error) {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = cfg.RequestTimeout()
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return &OpenAI{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  cfg.APIKey,
+		model:   cfg.Model,
+		http:    httpClient,
+	}, nil
+}
+
+// Chat completion wire types (only the fields we use)
+
+type openAIRequest struct {
+	Model             string          `json:"model"`
+	MaxTokens         int             `json:"max_tokens,omitempty"`
+	Messages          []openAIMessage `json:"messages"`
+	Tools             []openAITool    `json:"tools,omitempty"`
+	ToolChoice        string          `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Complete sends the request to the chat completions endpoint, requiring a
+// single tool call.
+func (p *OpenAI) Complete(ctx context.Context, req *Request) (Message, error) {
+	parallel := false
+	body, err := json.Marshal(openAIRequest{
+		Model:             p.model,
+		MaxTokens:         req.MaxTokens,
+		Messages:          toOpenAIMessages(req.System, req.Messages),
+		Tools:             toOpenAITools(req.Tools),
+		ToolChoice:        "required",
+		ParallelToolCalls: &parallel,
+	})
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.http.Do(httpReq)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to call OpenAI-compatible API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Message{}, &APIError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header),
+			Err:        fmt.Errorf("OpenAI-compatible API returned %s: %s", resp.Status, truncate(string(data), 500)),
+		}
+	}
+
+	var parsed openAIResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return Message{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return Message{}, fmt.Errorf("empty response from LLM")
+	}
+
+	choice := parsed.Choices[0].Message
+	msg := Message{
+		Role:  RoleAssistant,
+		Usage: Usage{InputTokens: parsed.Usage.PromptTokens, OutputTokens: parsed.Usage.CompletionTokens},
+	}
+	if choice.Content != "" {
+		msg.Content = append(msg.Content, TextBlock(choice.Content))
+	}
+	for _, call := range choice.ToolCalls {
+		msg.Content = append(msg.Content, Block{
+			Type:      BlockToolUse,
+			ToolID:    call.ID,
+			ToolName:  call.Function.Name,
+			ToolInput: json.RawMessage(call.Function.Arguments),
+		})
+	}
+	return msg, nil
+}
+
+// toOpenAITools declares every protocol action as a function tool.
+func toOpenAITools(specs []protocol.Tool) []openAITool {
+	tools := make([]openAITool, 0, len(specs))
+	for _, t := range specs {
+		tools = append(tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        string(t.Type),
+				Description: t.Description,
+				Parameters:  t.InputSchema(),
+			},
+		})
+	}
+	return tools
+}
+
+// toOpenAIMessages converts a transcript to chat messages. Tool results
+// become separate "tool" role messages ahead of the rest of their turn.
+func toOpenAIMessages(system string, messages []Message) []openAIMessage {
+	out := []openAIMessage{{Role: "system", Content: system}}
+
+	for _, msg := range messages {
+		if msg.Role == RoleAssistant {
+			am := openAIMessage{Role: "assistant"}
+			var text string
+			for _, block := range msg.Content {
+				switch block.Type {
+				case BlockText:
+					text += block.Text
+				case BlockToolUse:
+					call := openAIToolCall{ID: block.ToolID, Type: "function"}
+					call.Function.Name = block.ToolName
+					call.Function.Arguments = string(block.ToolInput)
+					am.ToolCalls = append(am.ToolCalls, call)
+				}
+			}
+			if text != "" {
+				am.Content = text
+			}
+			out = append(out, am)
+			continue
+		}
+
+		var parts []openAIContentPart
+		for _, block := range msg.Content {
+			switch block.Type {
+			case BlockToolResult:
+				content := block.Text
+				if block.IsError && !strings.HasPrefix(content, "ERROR") {
+					content = "ERROR: " + content
+				}
+				out = append(out, openAIMessage{Role: "tool", ToolCallID: block.ToolID, Content: content})
+			case BlockText:
+				parts = append(parts, openAIContentPart{Type: "text", Text: block.Text})
+			case BlockImage:
+				parts = append(parts, openAIContentPart{
+					Type:     "image_url",
+					ImageURL: &openAIImageURL{URL: "data:" + block.MediaType + ";base64," + block.Image},
+				})
+			}
+		}
+		if len(parts) > 0 {
+			out = append(out, openAIMessage{Role: "user", Content: parts})
+		}
+	}
+
+	return out
+}
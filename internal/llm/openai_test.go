@@ -0,0 +1,241 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// openAIStub is an OpenAI-compatible server that answers each request with
+// the next scripted response and keeps the requests it received.
+type openAIStub struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses []stubResponse
+	requests  []openAIRequest
+	auth      []string
+}
+
+type stubResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+// toolCallResponse is a chat completion calling one tool.
+func toolCallResponse(id, name, args string) stubResponse {
+	body, _ := json.Marshal(map[string]any{
+		"choices": []any{map[string]any{
+			"message": map[string]any{
+				"tool_calls": []any{map[string]any{
+					"id":       id,
+					"type":     "function",
+					"function": map[string]any{"name": name, "arguments": args},
+				}},
+			},
+		}},
+		"usage": map[string]any{"prompt_tokens": 100, "completion_tokens": 10},
+	})
+	return stubResponse{status: http.StatusOK, body: string(body)}
+}
+
+func (s *openAIStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path != "/chat/completions" {
+		s.t.Errorf("request to %s, want /chat/completions", r.URL.Path)
+	}
+	var req openAIRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.t.Errorf("undecodable request: %v", err)
+	}
+	s.requests = append(s.requests, req)
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+
+	if len(s.responses) == 0 {
+		s.t.Error("more requests than scripted responses")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	for k, v := range resp.header {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.status)
+	fmt.Fprint(w, resp.body)
+}
+
+// newStubClient starts stub and returns a client talking to it.
+func newStubClient(t *testing.T, responses ...stubResponse) (*Client, *openAIStub) {
+	t.Helper()
+	stub := &openAIStub{t: t, responses: responses}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.Provider = config.ProviderOpenAI
+	cfg.BaseURL = server.URL + "/"
+	cfg.APIKey = "test-key"
+	cfg.Model = "test-model"
+	cfg.MaxRetries = 2
+	cfg.RetryBaseMs = 1
+
+	provider, err := NewOpenAI(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClientWithProvider(provider, cfg), stub
+}
+
+var testShot = Screenshot{Data: "iVBORw0KGgo=", MediaType: "image/png", Description: "Display 1 of 1 (1280x720)."}
+
+func TestOpenAIToolCall(t *testing.T) {
+	client, stub := newStubClient(t, toolCallResponse("call_1", "click", `{"x": 120, "y": 340, "button": "right"}`))
+
+	act, err := client.GetAction(context.Background(), "open the menu", testShot, nil, nil)
+	if err != nil {
+		t.Fatalf("GetAction: %v", err)
+	}
+	if act.Type != protocol.ActionClick || act.X != 120 || act.Y != 340 || act.Button != "right" {
+		t.Errorf("action = %+v, want right click at (120, 340)", act)
+	}
+
+	req := stub.requests[0]
+	if req.Model != "test-model" || req.ToolChoice != "required" {
+		t.Errorf("model %q, tool choice %q", req.Model, req.ToolChoice)
+	}
+	if len(req.Tools) != len(protocol.Tools) {
+		t.Errorf("sent %d tools, want %d", len(req.Tools), len(protocol.Tools))
+	}
+	if stub.auth[0] != "Bearer test-key" {
+		t.Errorf("Authorization = %q", stub.auth[0])
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[1].Role != "user" {
+		t.Fatalf("messages = %+v, want system and user", req.Messages)
+	}
+	parts, _ := json.Marshal(req.Messages[1].Content)
+	if !strings.Contains(string(parts), "data:image/png;base64,iVBORw0KGgo=") {
+		t.Errorf("screenshot missing from user message: %s", parts)
+	}
+}
+
+func TestOpenAIInvalidToolCallCorrected(t *testing.T) {
+	client, stub := newStubClient(t,
+		toolCallResponse("call_1", "type", `{}`),
+		toolCallResponse("call_2", "type", `{"text": "hello"}`),
+	)
+	var rejected []*InvalidResponseError
+	client.OnInvalidResponse(func(err *InvalidResponseError) { rejected = append(rejected, err) })
+
+	act, err := client.GetAction(context.Background(), "type hello", testShot, nil, nil)
+	if err != nil {
+		t.Fatalf("GetAction: %v", err)
+	}
+	if act.Type != protocol.ActionType_ || act.Text != "hello" {
+		t.Errorf("action = %+v, want type hello", act)
+	}
+	if len(rejected) != 1 {
+		t.Fatalf("%d responses rejected, want 1", len(rejected))
+	}
+
+	// The correction answers the rejected call with an error tool result
+	if len(stub.requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(stub.requests))
+	}
+	messages := stub.requests[1].Messages
+	last := messages[len(messages)-1]
+	if last.Role != "tool" || last.ToolCallID != "call_1" {
+		t.Fatalf("last message = %+v, want a tool result for call_1", last)
+	}
+	if content, _ := last.Content.(string); !strings.HasPrefix(content, "ERROR") || !strings.Contains(content, "text is required") {
+		t.Errorf("tool result = %q, want the validation error", content)
+	}
+	if assistant := messages[len(messages)-2]; assistant.Role != "assistant" || len(assistant.ToolCalls) != 1 {
+		t.Errorf("rejected call not echoed back: %+v", assistant)
+	}
+}
+
+func TestOpenAIStatusRetry(t *testing.T) {
+	valid := toolCallResponse("call_1", "key", `{"key": "enter"}`)
+	tests := []struct {
+		name      string
+		responses []stubResponse
+		requests  int
+		retries   int
+		permanent bool
+	}{
+		{
+			name:      "server error retried",
+			responses: []stubResponse{{status: 503, body: `{"error": "busy"}`}, valid},
+			requests:  2,
+			retries:   1,
+		},
+		{
+			name: "rate limit retried",
+			responses: []stubResponse{
+				{status: 429, header: map[string]string{"Retry-After-Ms": "5"}, body: `{"error": "slow down"}`},
+				{status: 502, body: "bad gateway"},
+				valid,
+			},
+			requests: 3,
+			retries:  2,
+		},
+		{
+			name:      "retries exhausted",
+			responses: []stubResponse{{status: 500}, {status: 500}, {status: 500}},
+			requests:  3,
+			retries:   2,
+		},
+		{
+			name:      "bad key not retried",
+			responses: []stubResponse{{status: 401, body: `{"error": "invalid key"}`}},
+			requests:  1,
+			permanent: true,
+		},
+		{
+			name:      "bad request not retried",
+			responses: []stubResponse{{status: 400, body: `{"error": "bad request"}`}},
+			requests:  1,
+			permanent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, stub := newStubClient(t, tt.responses...)
+			var retries []RetryEvent
+			client.OnRetry(func(e RetryEvent) { retries = append(retries, e) })
+
+			_, err := client.GetAction(context.Background(), "press enter", testShot, nil, nil)
+			succeeded := tt.responses[len(tt.responses)-1].status == http.StatusOK
+			if succeeded != (err == nil) {
+				t.Fatalf("GetAction error = %v, want success %v", err, succeeded)
+			}
+			if len(stub.requests) != tt.requests {
+				t.Errorf("%d requests, want %d", len(stub.requests), tt.requests)
+			}
+			if len(retries) != tt.retries {
+				t.Errorf("%d retries, want %d", len(retries), tt.retries)
+			}
+
+			var permanent *PermanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("error %v permanent = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+			var apiErr *APIError
+			if err != nil && !errors.As(err, &apiErr) {
+				t.Errorf("error %v does not carry the API status", err)
+			}
+		})
+	}
+}
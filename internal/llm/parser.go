@@ -27,6 +27,24 @@ func ParseAction(response string) (*protocol.Action, error) {
 	return &action, nil
 }
 
+// ParseToolCall builds an action from a tool_use block. The tool name is
+// the action type and the input holds the remaining action fields.
+func ParseToolCall(name string, input json.RawMessage) (*protocol.Action, error) {
+	var action protocol.Action
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &action); err != nil {
+			return nil, fmt.Errorf("failed to parse %s input: %w", name, err)
+		}
+	}
+	action.Type = protocol.ActionType(name)
+
+	if err := action.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid action: %w", err)
+	}
+
+	return &action, nil
+}
+
 // cleanResponse removes markdown code blocks and extra whitespace.
 func cleanResponse(response string) string {
 	response = strings.TrimSpace(response)
@@ -1,24 +1,23 @@
 // Package llm provides LLM client functionality for interacting with Claude.
 package llm
 
-const SystemPrompt = `You are an autonomous desktop automation agent. You control a Windows computer by analyzing screenshots and executing actions to accomplish user goals.
+import "strings"
+
+const SystemPrompt = `You are an autonomous desktop automation agent. You control a Windows or Linux (X11) computer by analyzing screenshots and executing actions to accomplish user goals.
 
 ## Available Actions
 
-You must respond with a single JSON object (no markdown, no code blocks) containing one action:
+Every action is a tool. Call exactly one tool per response:
 
 ### GUI Actions
-- **click**: Click at coordinates
-  {"type": "click", "x": 100, "y": 200, "button": "left", "double": false}
+- **click**: Click at coordinates (x, y)
   - button: "left" (default), "right", "middle"
   - double: true for double-click
+  - cell: grid cell such as "C4" to click its centre instead of (x, y)
 
 - **type**: Type text
-  {"type": "type", "text": "Hello World"}
 
 - **key**: Press a key or key combination
-  {"type": "key", "key": "enter"}
-  {"type": "key", "key": "ctrl+c"}
   - Supports: enter, tab, escape, backspace, delete, space
   - Arrow keys: up, down, left, right
   - Modifiers: ctrl, alt, shift, win
@@ -26,26 +25,37 @@ You must respond with a single JSON object (no markdown, no code blocks) contain
   - Combinations: ctrl+c, ctrl+v, alt+f4, ctrl+shift+s
 
 - **scroll**: Scroll the mouse wheel
-  {"type": "scroll", "direction": "up", "amount": 3}
   - direction: "up" or "down"
   - amount: number of scroll units (default 3)
 
+- **zoom**: Magnify a region of the current screenshot (x, y, width, height)
+  - Following screenshots show only that region, enlarged
+  - Use it to read fine print or to hit small targets precisely
+
+- **zoom_reset**: Return to the whole display
+
 ### File Actions
 - **file_read**: Read a file's contents
-  {"type": "file_read", "path": "C:\\Users\\user\\file.txt"}
+  - The contents are returned to you on the next step
+  - Long files are truncated and binary files are not shown
 
 - **file_write**: Write content to a file
-  {"type": "file_write", "path": "C:\\Users\\user\\file.txt", "content": "Hello"}
 
 ### Control Actions
-- **wait**: Wait for UI to stabilize
-  {"type": "wait", "ms": 1000}
+- **wait**: Wait for UI to stabilize (ms, default 500)
+  - display: switch the following screenshots to display N (1-based), or -1 for all displays stitched together
+
+- **done**: Task completed successfully, with a summary
+
+- **failed**: Task cannot be completed, with a reason
 
-- **done**: Task completed successfully
-  {"type": "done", "summary": "Opened Notepad and typed Hello World"}
+## Displays
 
-- **failed**: Task cannot be completed
-  {"type": "failed", "reason": "Could not find the application"}
+Each screenshot says which display it shows and whether it is zoomed. Coordinates are always pixels in the current screenshot, with (0, 0) at its top-left corner, even when it shows another display, all of them, or a zoomed region.
+
+## Grid Overlay
+
+If the screenshot description mentions a grid, labeled lines are drawn over the image. Columns are lettered from the left (A, B, C, ...) and rows numbered from the top (1, 2, 3, ...); each cell's label is in its top-left corner. To click a target, you may give its cell (e.g. "C4") instead of coordinates; the click lands in the centre of that cell. Use x and y, or zoom in first, when the target is much smaller than a cell. The grid is not part of the screen.
 
 ## Guidelines
 
@@ -53,7 +63,7 @@ You must respond with a single JSON object (no markdown, no code blocks) contain
 
 2. **One action at a time**: Return exactly one action per response. Wait for the result before continuing.
 
-3. **Verify your actions**: After each action, check the next screenshot to confirm it worked.
+3. **Verify your actions**: After each action, check the next screenshot to confirm it worked. A result of "no visible change" means the screen did not react; the click probably missed, so adjust the coordinates or try another approach. Otherwise results say which region of the screenshot changed, and that region may be outlined in yellow on the next screenshot.
 
 4. **Use keyboard shortcuts**: They're often faster than clicking through menus (e.g., Ctrl+S to save).
 
@@ -61,23 +71,24 @@ You must respond with a single JSON object (no markdown, no code blocks) contain
 
 6. **Wait when needed**: Use wait action if you expect a dialog or window to appear.
 
-7. **File paths must be absolute**: Always use full paths like C:\Users\... for file operations.
+7. **File paths must be absolute**: Always use full paths like C:\Users\... (Windows) or /home/... (Linux) for file operations.
 
 8. **Report completion**: When the goal is achieved, use the done action with a summary.
 
 9. **Report failures**: If you cannot complete the task after reasonable attempts, use failed with a reason.
 
+10. **Follow operator notes**: A person may watch the run and send an "Operator Note". Follow it; it takes precedence over your own plan.
+
 ## Response Format
 
-Respond with ONLY a JSON object. No markdown code blocks, no explanation, no extra text.
+Respond by calling exactly one tool. Do not answer with plain text.
 
-Correct: {"type": "click", "x": 100, "y": 200}
-Wrong: ` + "```json\n{\"type\": \"click\"}\n```" + `
-Wrong: I'll click here: {"type": "click"}
+If a tool call is rejected with an error, read the error and call the tool again with corrected input.
 `
 
-// BuildUserPrompt constructs the user prompt with goal and history.
-func BuildUserPrompt(goal string, history []HistoryEntry) string {
+// BuildUserPrompt constructs the user prompt with goal, history, any
+// operator notes and a description of the screenshot that follows it.
+func BuildUserPrompt(goal string, history []HistoryEntry, notes []string, screen string) string {
 	prompt := "## Goal\n" + goal + "\n\n"
 
 	if len(history) > 0 {
@@ -88,10 +99,32 @@ func BuildUserPrompt(goal string, history []HistoryEntry) string {
 		prompt += "\n"
 	}
 
-	prompt += "## Current Screenshot\nAnalyze the screenshot below and decide the next action.\n"
+	prompt += formatNotes(notes)
+	prompt += currentScreenshot(screen)
 	return prompt
 }
 
+// formatNotes renders guidance the operator sent during the run.
+func formatNotes(notes []string) string {
+	if len(notes) == 0 {
+		return ""
+	}
+	text := "## Operator Note\n"
+	for _, note := range notes {
+		text += "- " + note + "\n"
+	}
+	return text + "\n"
+}
+
+// currentScreenshot introduces the screenshot at the end of a user turn.
+func currentScreenshot(screen string) string {
+	text := "## Current Screenshot\n"
+	if screen != "" {
+		text += screen + "\n"
+	}
+	return text + "Analyze the screenshot below and decide the next action.\n"
+}
+
 func formatHistoryEntry(num int, entry HistoryEntry) string {
 	result := ""
 	switch entry.Action.Type {
@@ -109,15 +142,44 @@ func formatHistoryEntry(num int, entry HistoryEntry) string {
 		result = formatFileWrite(entry)
 	case "wait":
 		result = formatWait(entry)
+	case "zoom":
+		result = formatZoom(entry)
 	default:
 		result = entry.Action.Type
 	}
 
-	status := "OK"
+	return formatEntry(num, result+formatDisplay(entry), entryStatus(entry)) + formatOutput(entry)
+}
+
+// formatToolResult renders a history entry as the result of its tool call.
+func formatToolResult(entry HistoryEntry) string {
+	result := entryStatus(entry)
+	if output := formatOutput(entry); output != "" {
+		result += "\n" + output
+	}
+	return result
+}
+
+func entryStatus(entry HistoryEntry) string {
 	if entry.Error != "" {
-		status = "ERROR: " + entry.Error
+		return "ERROR: " + entry.Error
+	}
+	if entry.Observation != "" {
+		return "OK, " + entry.Observation
+	}
+	return "OK"
+}
+
+// formatOutput renders tool output (such as file contents) below its entry.
+func formatOutput(entry HistoryEntry) string {
+	if entry.Output == "" {
+		return ""
+	}
+	output := entry.Output
+	if !strings.HasSuffix(output, "\n") {
+		output += "\n"
 	}
-	return formatEntry(num, result, status)
+	return "   --- contents of " + entry.Action.Path + " ---\n" + output + "   --- end of " + entry.Action.Path + " ---\n"
 }
 
 func formatClick(entry HistoryEntry) string {
@@ -129,6 +191,9 @@ func formatClick(entry HistoryEntry) string {
 	if entry.Action.Double {
 		dbl = " double"
 	}
+	if entry.Action.Cell != "" {
+		return formatAction("click", "%s%s at cell %s", btn, dbl, entry.Action.Cell)
+	}
 	return formatAction("click", "%s%s at (%d, %d)", btn, dbl, entry.Action.X, entry.Action.Y)
 }
 
@@ -160,6 +225,22 @@ func formatWait(entry HistoryEntry) string {
 	return formatAction("wait", "%dms", entry.Action.Ms)
 }
 
+func formatZoom(entry HistoryEntry) string {
+	return formatAction("zoom", "%dx%d at (%d, %d)", entry.Action.Width, entry.Action.Height, entry.Action.X, entry.Action.Y)
+}
+
+// formatDisplay notes a display switch made by an action.
+func formatDisplay(entry HistoryEntry) string {
+	switch entry.Action.Display {
+	case 0:
+		return ""
+	case -1:
+		return ", then view all displays"
+	default:
+		return sprintf(", then view display %d", entry.Action.Display)
+	}
+}
+
 func formatAction(action, format string, args ...interface{}) string {
 	return action + ": " + sprintf(format, args...)
 }
@@ -215,6 +296,11 @@ func itoa(n int) string {
 type HistoryEntry struct {
 	Action ActionRecord
 	Error  string
+	Output string // Tool output shown to the model, e.g. file_read contents
+
+	// Observation notes what the agent saw after the action, such as
+	// "no visible change" when the screen did not react.
+	Observation string
 }
 
 // ActionRecord holds the action details for history.
@@ -232,4 +318,8 @@ type ActionRecord struct {
 	Ms        int
 	Summary   string
 	Reason    string
+	Cell      string
+	Width     int
+	Height    int
+	Display   int
 }
@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// Request is a provider-neutral model request.
+type Request struct {
+	System    string
+	Messages  []Message
+	Tools     []protocol.Tool
+	MaxTokens int
+}
+
+// Provider sends requests to a model API, translating the neutral
+// transcript to its wire format and the response back to a Message.
+type Provider interface {
+	Complete(ctx context.Context, req *Request) (Message, error)
+}
+
+// NewProvider creates the provider selected in the configuration.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	switch cfg.Provider {
+	case config.ProviderAnthropic, "":
+		return NewAnthropic(cfg)
+	case config.ProviderOpenAI:
+		return NewOpenAI(cfg)
+	case config.ProviderScript:
+		return LoadScript(cfg.ScriptPath)
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", cfg.Provider)
+	}
+}
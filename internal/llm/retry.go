@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/config"
+)
+
+// maxRetryDelay caps the exponential backoff between attempts.
+const maxRetryDelay = 30 * time.Second
+
+// RetryPolicy controls how failed provider calls are retried.
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt
+	BaseDelay  time.Duration // delay before the first retry, doubled each time
+	Budget     time.Duration // total time allowed for retries, 0 for no limit
+}
+
+// RetryPolicyFromConfig builds the retry policy from cfg.
+func RetryPolicyFromConfig(cfg *config.Config) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: cfg.MaxRetries,
+		BaseDelay:  time.Duration(cfg.RetryBaseMs) * time.Millisecond,
+		Budget:     time.Duration(cfg.RetryBudgetSeconds) * time.Second,
+	}
+}
+
+// RetryEvent describes a retry that is about to happen.
+type RetryEvent struct {
+	Retry      int // 1 for the first retry
+	MaxRetries int
+	Delay      time.Duration
+	Err        error // the error that triggered the retry
+}
+
+// APIError is an error response from a model API.
+type APIError struct {
+	StatusCode int
+	RetryAfter time.Duration // from the retry-after header, 0 if absent
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError wraps a failure that retrying will not fix, such as an
+// invalid API key or a malformed request.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// retryable reports whether err is worth retrying and any delay the server
+// asked for.
+func retryable(err error) (bool, time.Duration) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch code := apiErr.StatusCode; {
+		case code == http.StatusRequestTimeout, code == http.StatusConflict,
+			code == http.StatusTooManyRequests, code >= 500:
+			// 529 (overloaded) falls under >= 500
+			return true, apiErr.RetryAfter
+		default:
+			return false, 0
+		}
+	}
+
+	// Per-request timeouts and dropped connections
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true, 0
+	}
+	return false, 0
+}
+
+// parseRetryAfter reads the retry-after-ms or retry-after header.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// backoff returns the delay before the given retry: exponential growth from
+// BaseDelay with jitter between half and the full value.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// do calls fn until it succeeds, fails permanently, or the policy runs out
+// of retries or budget. onRetry, if set, is told about each retry.
+func (p RetryPolicy) do(ctx context.Context, onRetry func(RetryEvent), fn func() error) error {
+	start := time.Now()
+	for retry := 1; ; retry++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		ok, retryAfter := retryable(err)
+		if !ok {
+			return &PermanentError{Err: err}
+		}
+		if retry > p.MaxRetries {
+			return fmt.Errorf("giving up after %d attempts: %w", retry, err)
+		}
+
+		delay := p.backoff(retry)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if p.Budget > 0 && time.Since(start)+delay > p.Budget {
+			return fmt.Errorf("retry budget of %s exhausted: %w", p.Budget, err)
+		}
+
+		if onRetry != nil {
+			onRetry(RetryEvent{Retry: retry, MaxRetries: p.MaxRetries, Delay: delay, Err: err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// ScriptStep is one scripted reply. A step with Screenshot set answers any
+// request whose current screenshot hash starts with it; a step with Step
+// set answers that request number (1-based); other steps are replayed in
+// file order. A verifier's script gives a Verdict instead of an Action.
+type ScriptStep struct {
+	Step       int               `json:"step,omitempty"`
+	Screenshot string            `json:"screenshot,omitempty"` // hex SHA-256 of the decoded image, prefix allowed
+	Action     protocol.Action   `json:"action"`
+	Verdict    *protocol.Verdict `json:"verdict,omitempty"`
+}
+
+// Script is the file format read by the scripted provider. A bare JSON
+// array of steps is accepted as well.
+type Script struct {
+	Steps []ScriptStep `json:"steps"`
+}
+
+// Scripted is a deterministic Provider that replays actions from a script
+// instead of calling a model, for offline tests and demos.
+type Scripted struct {
+	mu    sync.Mutex
+	steps []ScriptStep
+	used  []bool
+	calls int
+}
+
+// NewScripted creates a scripted provider from a list of steps.
+func NewScripted(steps []ScriptStep) *Scripted {
+	return &Scripted{
+		steps: steps,
+		used:  make([]bool, len(steps)),
+	}
+}
+
+// LoadScript reads a script file and creates a scripted provider.
+func LoadScript(path string) (*Scripted, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	var script Script
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &script.Steps)
+	} else {
+		err = json.Unmarshal(data, &script)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+	if len(script.Steps) == 0 {
+		return nil, fmt.Errorf("script %s has no steps", path)
+	}
+
+	return NewScripted(script.Steps), nil
+}
+
+// Complete replies with the scripted action, or verdict, for this request
+// as a tool call.
+func (p *Scripted) Complete(ctx context.Context, req *Request) (Message, error) {
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+
+	step, ok := p.match(p.calls, ScreenshotHash(req.Messages))
+	if !ok {
+		return Message{}, fmt.Errorf("script has no action for request %d", p.calls)
+	}
+
+	var reply any = step.Action
+	name := string(step.Action.Type)
+	if step.Verdict != nil {
+		reply, name = step.Verdict, string(step.Verdict.Type)
+	}
+	input, err := json.Marshal(reply)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to encode scripted %s: %w", name, err)
+	}
+
+	return Message{
+		Role: RoleAssistant,
+		Content: []Block{{
+			Type:      BlockToolUse,
+			ToolID:    fmt.Sprintf("script-%d", p.calls),
+			ToolName:  name,
+			ToolInput: input,
+		}},
+	}, nil
+}
+
+// match picks the step for a request: screenshot matches first, then an
+// explicit step number, then the next unused sequential step.
+func (p *Scripted) match(call int, hash string) (ScriptStep, bool) {
+	if hash != "" {
+		for _, s := range p.steps {
+			if s.Screenshot != "" && strings.HasPrefix(hash, strings.ToLower(s.Screenshot)) {
+				return s, true
+			}
+		}
+	}
+	for _, s := range p.steps {
+		if s.Step == call {
+			return s, true
+		}
+	}
+	for i, s := range p.steps {
+		if s.Step == 0 && s.Screenshot == "" && !p.used[i] {
+			p.used[i] = true
+			return s, true
+		}
+	}
+	return ScriptStep{}, false
+}
+
+// ScreenshotHash returns the hex SHA-256 of the newest screenshot in the
+// transcript, or "" if there is none.
+func ScreenshotHash(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		content := messages[i].Content
+		for j := len(content) - 1; j >= 0; j-- {
+			if content[j].Type != BlockImage {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(content[j].Image)
+			if err != nil {
+				return ""
+			}
+			sum := sha256.Sum256(data)
+			return hex.EncodeToString(sum[:])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// VerifierPrompt is the system prompt for verifier agents, which review the
+// work of a primary agent instead of acting on the goal themselves.
+const VerifierPrompt = `You are a verifier reviewing the work of an autonomous desktop automation agent. The agent controls a Windows or Linux (X11) computer with mouse, keyboard and file actions to accomplish a user's goal.
+
+You receive the goal, the agent's action history, the files it wrote and a screenshot of the current screen. Judge whether the work so far is correct, from the evidence rather than from the agent's own claims.
+
+## Verdicts
+
+Every verdict is a tool. Call exactly one tool per response:
+
+- **approve**: The work is correct. Give a short summary of what you checked.
+
+- **reject**: The work is wrong or incomplete. List each specific problem in issues, precisely enough for the agent to fix it (e.g. "The file is saved as notes.txt, not todo.txt").
+
+- **remediate**: The problems are small and you can fix them yourself. Give the actions that fix them, in order, and the issues they address. Actions use the same fields as the agent's actions: click (x, y in screenshot pixels), type (text), key (key), scroll (direction, amount), file_write (path, content), wait (ms).
+
+## Guidelines
+
+1. **Check the result, not the effort**: Approve only if the goal is actually achieved, or, when reviewing a single step, if that step moved toward it without doing damage.
+
+2. **Be specific**: Vague issues such as "something is wrong" cannot be acted on.
+
+3. **Do not nitpick**: Reject only for problems that matter to the goal.
+
+4. **Prefer reject over remediate** unless the fix is short and certain.
+
+## Response Format
+
+Respond by calling exactly one tool. Do not answer with plain text.
+`
+
+// BuildReviewPrompt constructs the verifier's prompt with the goal, the
+// agent's history and the files it wrote. final is set when the agent has
+// reported the goal done; otherwise the latest action is under review.
+func BuildReviewPrompt(goal string, history []HistoryEntry, files []string, final bool, screen string) string {
+	prompt := "## Goal\n" + goal + "\n\n"
+
+	if len(history) > 0 {
+		prompt += "## Agent's Action History\n"
+		for i, entry := range history {
+			prompt += formatHistoryEntry(i+1, entry)
+		}
+		prompt += "\n"
+	}
+
+	if len(files) > 0 {
+		prompt += "## Files Written\n"
+		for _, f := range files {
+			prompt += "- " + f + "\n"
+		}
+		prompt += "\n"
+	}
+
+	prompt += "## Review\n"
+	if final {
+		prompt += "The agent reports that the goal is achieved. Check whether it really is.\n\n"
+	} else {
+		prompt += "Check the agent's latest action and its effect on the screen.\n\n"
+	}
+
+	prompt += "## Current Screenshot\n"
+	if screen != "" {
+		prompt += screen + "\n"
+	}
+	return prompt + "Review the screenshot below and give your verdict.\n"
+}
+
+// GetVerdict asks the model to review an agent's work and returns its
+// verdict. Each review is a fresh conversation.
+func (c *Client) GetVerdict(ctx context.Context, goal string, shot Screenshot, history []HistoryEntry, files []string, final bool) (*protocol.Verdict, error) {
+	conv := NewConversation(1)
+	conv.Ask(BuildReviewPrompt(goal, history, files, final, shot.Description), shot)
+
+	var verdict *protocol.Verdict
+	err := c.complete(ctx, conv, VerifierPrompt, protocol.VerdictTools, func(reply Message) error {
+		var err error
+		verdict, err = parseVerdict(reply)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verdict, nil
+}
+
+// parseVerdict extracts the verdict from a response.
+func parseVerdict(reply Message) (*protocol.Verdict, error) {
+	var verdict protocol.Verdict
+	var responseText string
+	found := false
+	for _, block := range reply.Content {
+		if block.Type == BlockToolUse {
+			if len(block.ToolInput) > 0 {
+				if err := json.Unmarshal(block.ToolInput, &verdict); err != nil {
+					return nil, fmt.Errorf("failed to parse %s input: %w", block.ToolName, err)
+				}
+			}
+			verdict.Type = protocol.VerdictType(block.ToolName)
+			found = true
+			break
+		}
+		if block.Type == BlockText && responseText == "" {
+			responseText = block.Text
+		}
+	}
+
+	if !found {
+		if responseText == "" {
+			return nil, fmt.Errorf("no tool call or text response from LLM")
+		}
+		// Fall back to a JSON verdict in plain text
+		if err := json.Unmarshal([]byte(cleanResponse(responseText)), &verdict); err != nil {
+			return nil, fmt.Errorf("failed to parse verdict JSON: %w\nResponse was: %s", err, truncate(responseText, 200))
+		}
+	}
+
+	if err := verdict.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid verdict: %w", err)
+	}
+	return &verdict, nil
+}
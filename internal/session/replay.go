@@ -0,0 +1,173 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg" // recorded screenshot formats
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// ReplayOptions controls how a recorded session is replayed.
+type ReplayOptions struct {
+	// Delay is an extra pause after each action, on top of waiting for the
+	// screen to settle for Settle (up to SettleTimeout).
+	Delay         time.Duration
+	Settle        time.Duration
+	SettleTimeout time.Duration
+
+	// Compare checks the screen against the recorded screenshot before each
+	// step. A step diverges when more than Tolerance of the pixels differ.
+	Compare   bool
+	Tolerance float64
+
+	// KeepGoing continues after a divergence instead of stopping.
+	KeepGoing bool
+
+	RequireAbsolutePaths bool
+}
+
+// ReplayEvent reports the outcome of one replayed step.
+type ReplayEvent struct {
+	Step     int
+	Action   *protocol.Action
+	Result   *action.Result // nil when the step was not executed
+	Diff     float64        // share of pixels differing from the recording, -1 if not compared
+	Diverged bool
+	Reason   string // why the step diverged or was skipped
+}
+
+// DivergenceError stops a replay whose screen or results no longer match
+// the recording.
+type DivergenceError struct {
+	Step   int
+	Reason string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("replay diverged at step %d: %s", e.Step, e.Reason)
+}
+
+// Replay re-executes the actions recorded in dir through backend without
+// calling a model. Steps whose action failed or never ran during
+// recording, such as an attempt interrupted by a pause, and the final done
+// or failed step, are skipped. onStep, if set, is told about every step.
+func Replay(ctx context.Context, dir string, backend input.Backend, opts ReplayOptions, onStep func(ReplayEvent)) error {
+	return ReplayFrom(ctx, dir, backend, capture.NewSource(), opts, onStep)
+}
+
+// ReplayFrom replays a session like Replay, comparing against screenshots
+// taken from src instead of the screen, e.g. synthetic images in tests.
+func ReplayFrom(ctx context.Context, dir string, backend input.Backend, src capture.Source, opts ReplayOptions, onStep func(ReplayEvent)) error {
+	info, steps, err := Load(dir)
+	if err != nil {
+		return err
+	}
+
+	screen := capture.NewCapturerFrom(src, info.Display, info.MaxEdge)
+	screen.SetGrid(info.GridCellSize)
+	executor := action.NewExecutor(backend, screen, opts.RequireAbsolutePaths)
+
+	notify := func(event ReplayEvent) {
+		if onStep != nil {
+			onStep(event)
+		}
+	}
+
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		event := ReplayEvent{Step: step.Step, Action: step.Action, Diff: -1}
+		switch {
+		case step.Action == nil:
+			event.Reason = "no action recorded"
+			notify(event)
+			continue
+		case step.Action.Type == protocol.ActionDone, step.Action.Type == protocol.ActionFailed:
+			event.Reason = "end of recording"
+			notify(event)
+			continue
+		case step.Result == nil:
+			event.Reason = "action not executed when recorded"
+			notify(event)
+			continue
+		case !step.Result.Success:
+			event.Reason = "action failed when recorded"
+			notify(event)
+			continue
+		}
+
+		// A fresh capture also sets the frame that coordinates refer to
+		frame, err := screen.Capture()
+		if err != nil {
+			return fmt.Errorf("failed to capture screenshot: %w", err)
+		}
+
+		// The recording holds what the model saw, overlays included
+		if opts.Compare && step.Screenshot != "" {
+			recorded, err := loadImage(filepath.Join(dir, step.Screenshot))
+			if err != nil {
+				return err
+			}
+			event.Diff = capture.Diff(recorded, frame.Annotated())
+			if event.Diff > opts.Tolerance {
+				event.Diverged = true
+				event.Reason = fmt.Sprintf("screen differs from the recording (%.1f%% of pixels)", event.Diff*100)
+			}
+		}
+
+		if !event.Diverged {
+			event.Result = executor.Execute(step.Action)
+			if !event.Result.Success {
+				event.Diverged = true
+				event.Reason = "action failed: " + event.Result.Error
+			}
+		}
+
+		notify(event)
+		if event.Diverged && !opts.KeepGoing {
+			return &DivergenceError{Step: step.Step, Reason: event.Reason}
+		}
+
+		if event.Result != nil {
+			_, _ = screen.WaitStable(ctx, opts.Settle, opts.SettleTimeout)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.Delay):
+			}
+		}
+	}
+	return nil
+}
+
+// loadImage decodes a recorded screenshot.
+func loadImage(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recorded screenshot: %w", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba, nil
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
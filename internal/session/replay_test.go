@@ -0,0 +1,224 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// screen is a capture.Source with one display whose image can be swapped.
+type screen struct {
+	mu  sync.Mutex
+	img *image.RGBA
+}
+
+func (s *screen) Displays() []image.Rectangle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return []image.Rectangle{s.img.Bounds()}
+}
+
+func (s *screen) CaptureRect(bounds image.Rectangle) (*image.RGBA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		copy(out.Pix[y*out.Stride:(y+1)*out.Stride], s.img.Pix[s.img.PixOffset(bounds.Min.X, bounds.Min.Y+y):])
+	}
+	return out, nil
+}
+
+func (s *screen) show(img *image.RGBA) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.img = img
+}
+
+// screens returns a 1280x720 test screen and the same screen with a
+// dialog covering about 4% of it.
+func screens() (before, after *image.RGBA) {
+	before = image.NewRGBA(image.Rect(0, 0, 1280, 720))
+	for y := 0; y < 720; y++ {
+		for x := 0; x < 1280; x++ {
+			before.Set(x, y, color.RGBA{uint8(x / 5), uint8(y / 3), 80, 255})
+		}
+	}
+	after = image.NewRGBA(before.Rect)
+	copy(after.Pix, before.Pix)
+	for y := 300; y < 500; y++ {
+		for x := 500; x < 700; x++ {
+			after.Set(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	return before, after
+}
+
+// recordFixture records a session of five steps: a click on the first
+// screen, a type that failed, a key press interrupted before it ran, the
+// key press again on the second screen, and done.
+func recordFixture(t *testing.T) string {
+	t.Helper()
+	before, after := screens()
+
+	rec, err := NewRecorder(t.TempDir(), Info{Goal: "open the dialog"}, "system prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		step   Step
+		screen *image.RGBA
+	}{
+		{Step{Action: &protocol.Action{Type: protocol.ActionClick, X: 100, Y: 50, Button: "left"}, Result: &action.Result{Success: true}}, before},
+		{Step{Action: &protocol.Action{Type: protocol.ActionType_, Text: "x"}, Result: &action.Result{Error: "no focus"}}, before},
+		{Step{Action: &protocol.Action{Type: protocol.ActionKey, Key: "enter"}, Error: "context canceled"}, after},
+		{Step{Action: &protocol.Action{Type: protocol.ActionKey, Key: "enter"}, Result: &action.Result{Success: true}}, after},
+		{Step{Action: &protocol.Action{Type: protocol.ActionDone, Summary: "opened"}}, after},
+	}
+	for i, s := range steps {
+		step := s.step
+		step.Step = i + 1
+		step.Time = time.Now()
+		encoded, err := capture.Encode(s.screen, config.FormatPNG, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if step.Screenshot, err = rec.SaveScreenshot(step.Step, encoded.MediaType, encoded.Data); err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.WriteStep(&step); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Finish("completed", "opened", nil); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Dir()
+}
+
+// clickingBackend records input and shows the second screen once clicked.
+type clickingBackend struct {
+	*input.Recorder
+	screen *screen
+	next   *image.RGBA
+}
+
+func (b *clickingBackend) Click(button string, double bool) error {
+	b.screen.show(b.next)
+	return b.Recorder.Click(button, double)
+}
+
+var replayOpts = ReplayOptions{
+	Settle:        10 * time.Millisecond,
+	SettleTimeout: 50 * time.Millisecond,
+	Compare:       true,
+	Tolerance:     0.01,
+}
+
+func TestReplayMatching(t *testing.T) {
+	dir := recordFixture(t)
+	before, after := screens()
+	src := &screen{img: before}
+	backend := &clickingBackend{Recorder: input.NewRecorder(), screen: src, next: after}
+
+	var events []ReplayEvent
+	err := ReplayFrom(context.Background(), dir, backend, src, replayOpts, func(e ReplayEvent) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	want := []input.Event{
+		{Kind: "move", X: 100, Y: 50},
+		{Kind: "click", Button: "left"},
+		{Kind: "key_press", Key: "enter"},
+	}
+	if got := backend.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("input = %+v, want %+v", got, want)
+	}
+
+	if len(events) != 5 {
+		t.Fatalf("%d events, want 5", len(events))
+	}
+	for _, i := range []int{0, 3} {
+		if e := events[i]; e.Diverged || e.Diff != 0 || e.Result == nil || !e.Result.Success {
+			t.Errorf("step %d = %+v, want a matching, successful step", e.Step, e)
+		}
+	}
+	if e := events[1]; e.Result != nil || e.Reason != "action failed when recorded" {
+		t.Errorf("failed step = %+v, want it skipped", e)
+	}
+	if e := events[2]; e.Result != nil || e.Reason != "action not executed when recorded" {
+		t.Errorf("interrupted step = %+v, want it skipped", e)
+	}
+	if e := events[4]; e.Result != nil || e.Reason != "end of recording" {
+		t.Errorf("done step = %+v, want it skipped", e)
+	}
+}
+
+func TestReplayDiverging(t *testing.T) {
+	before, _ := screens()
+
+	tests := []struct {
+		name      string
+		keepGoing bool
+		tolerance float64
+		wantErr   bool
+		wantKeys  bool
+	}{
+		{name: "stops", tolerance: 0.01, wantErr: true},
+		{name: "keeps going", tolerance: 0.01, keepGoing: true},
+		{name: "within tolerance", tolerance: 0.1, wantKeys: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := recordFixture(t)
+
+			// The screen never shows the dialog the key press was made on
+			backend := input.NewRecorder()
+			opts := replayOpts
+			opts.KeepGoing = tt.keepGoing
+			opts.Tolerance = tt.tolerance
+
+			var events []ReplayEvent
+			err := ReplayFrom(context.Background(), dir, backend, &screen{img: before}, opts, func(e ReplayEvent) {
+				events = append(events, e)
+			})
+
+			var divergence *DivergenceError
+			if gotErr := errors.As(err, &divergence); gotErr != tt.wantErr {
+				t.Fatalf("Replay error = %v, want divergence %v", err, tt.wantErr)
+			}
+			if tt.wantErr && divergence.Step != 4 {
+				t.Errorf("diverged at step %d, want 4", divergence.Step)
+			}
+
+			pressed := false
+			for _, e := range backend.Events() {
+				pressed = pressed || e.Key == "enter"
+			}
+			if pressed != tt.wantKeys {
+				t.Errorf("enter pressed = %v, want %v", pressed, tt.wantKeys)
+			}
+
+			keyStep := events[3]
+			if keyStep.Diff < 0.03 || keyStep.Diff > 0.05 {
+				t.Errorf("key step differs by %.3f, want about 0.043", keyStep.Diff)
+			}
+			if keyStep.Diverged == tt.wantKeys {
+				t.Errorf("key step diverged = %v, want %v", keyStep.Diverged, !tt.wantKeys)
+			}
+		})
+	}
+}
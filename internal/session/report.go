@@ -0,0 +1,224 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// ReportFile is the default name of the HTML report in a session directory.
+const ReportFile = "report.html"
+
+// report is the data rendered by reportTemplate.
+type report struct {
+	Info     *Info
+	Steps    []reportStep
+	Duration time.Duration
+	Usage    llm.Usage
+}
+
+// reportStep is a recorded step prepared for display.
+type reportStep struct {
+	Step
+	Image  template.URL // screenshot as a data URI, empty if none was saved
+	Marker *marker
+	Detail string
+	Usage  llm.Usage
+}
+
+// marker locates the target of an action on the screenshot, in percent of
+// the image size. A click is a point; a zoom is a rectangle.
+type marker struct {
+	Left, Top     float64
+	Width, Height float64
+}
+
+// WriteReport renders the session recorded in dir as a self-contained HTML
+// page, with every screenshot embedded.
+func WriteReport(w io.Writer, dir string) error {
+	info, steps, err := Load(dir)
+	if err != nil {
+		return err
+	}
+
+	r := report{Info: info}
+	if !info.Finished.IsZero() {
+		r.Duration = info.Finished.Sub(info.Started).Round(time.Second)
+	}
+
+	for _, step := range steps {
+		rs := reportStep{Step: step}
+		if step.Action != nil {
+			rs.Detail = action.Describe(step.Action)
+		}
+		for _, call := range step.Calls {
+			rs.Usage.InputTokens += call.Usage.InputTokens
+			rs.Usage.OutputTokens += call.Usage.OutputTokens
+		}
+		r.Usage.InputTokens += rs.Usage.InputTokens
+		r.Usage.OutputTokens += rs.Usage.OutputTokens
+
+		if step.Screenshot != "" {
+			data, err := os.ReadFile(filepath.Join(dir, step.Screenshot))
+			if err != nil {
+				return fmt.Errorf("failed to read recorded screenshot: %w", err)
+			}
+			mediaType := "image/jpeg"
+			if strings.HasSuffix(step.Screenshot, ".png") {
+				mediaType = "image/png"
+			}
+			rs.Image = template.URL("data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data))
+
+			if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && step.Action != nil {
+				rs.Marker = markerFor(step.Action, image.Pt(cfg.Width, cfg.Height), info.GridCellSize)
+			}
+		}
+		r.Steps = append(r.Steps, rs)
+	}
+
+	if err := reportTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// markerFor returns where act pointed on a screenshot of the given size,
+// or nil if it has no screen target.
+func markerFor(act *protocol.Action, size image.Point, gridCellSize int) *marker {
+	if size.X == 0 || size.Y == 0 {
+		return nil
+	}
+	percent := func(v, of int) float64 { return float64(v) * 100 / float64(of) }
+
+	switch act.Type {
+	case protocol.ActionClick:
+		x, y := act.X, act.Y
+		if act.Cell != "" {
+			if gridCellSize <= 0 {
+				return nil
+			}
+			var err error
+			if x, y, err = capture.NewGrid(size, gridCellSize).Cell(act.Cell); err != nil {
+				return nil
+			}
+		}
+		return &marker{Left: percent(x, size.X), Top: percent(y, size.Y)}
+	case protocol.ActionZoom:
+		return &marker{
+			Left:   percent(act.X, size.X),
+			Top:    percent(act.Y, size.Y),
+			Width:  percent(act.Width, size.X),
+			Height: percent(act.Height, size.Y),
+		}
+	default:
+		return nil
+	}
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(ms int64) string {
+		return (time.Duration(ms) * time.Millisecond).Round(10 * time.Millisecond).String()
+	},
+	"pct": func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"terminal": func(act *protocol.Action) bool {
+		return act != nil && (act.Type == protocol.ActionDone || act.Type == protocol.ActionFailed)
+	},
+	"verdict": action.DescribeVerdict,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GoLemming run: {{.Info.Goal}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1100px; color: #222; }
+h1 { font-size: 1.4em; }
+table.summary td { padding: 2px 12px 2px 0; vertical-align: top; }
+table.summary td:first-child { color: #666; }
+.state-completed { color: #1a7f37; font-weight: bold; }
+.state-failed, .state-stopped { color: #cf222e; font-weight: bold; }
+.step { border-top: 1px solid #ddd; padding: 1em 0; }
+.step h2 { font-size: 1.1em; margin: 0 0 .5em; }
+.step h2 .detail { font-weight: normal; color: #555; }
+.meta { color: #666; font-size: .9em; }
+.ok { color: #1a7f37; }
+.error { color: #cf222e; }
+.shot { position: relative; display: inline-block; max-width: 100%; margin-top: .5em; }
+.shot img { display: block; max-width: 100%; border: 1px solid #ccc; }
+.point { position: absolute; width: 24px; height: 24px; margin: -14px 0 0 -14px; border: 3px solid #ff2d55; border-radius: 50%; box-shadow: 0 0 0 2px #fff; }
+.area { position: absolute; border: 3px solid #ff2d55; box-shadow: 0 0 0 2px #fff; }
+details { margin-top: .5em; }
+pre { white-space: pre-wrap; background: #f6f8fa; padding: .5em; font-size: .85em; }
+</style>
+</head>
+<body>
+<h1>{{.Info.Goal}}</h1>
+<table class="summary">
+<tr><td>Outcome</td><td><span class="state-{{.Info.State}}">{{.Info.State}}</span>{{if .Info.Result}}: {{.Info.Result}}{{end}}</td></tr>
+{{- if .Info.Error}}
+<tr><td>Error</td><td class="error">{{.Info.Error}}</td></tr>
+{{- end}}
+<tr><td>Started</td><td>{{.Info.Started.Format "2006-01-02 15:04:05"}}{{if .Duration}} ({{.Duration}}){{end}}</td></tr>
+{{- if .Info.Model}}
+<tr><td>Model</td><td>{{.Info.Provider}} {{.Info.Model}}</td></tr>
+{{- end}}
+<tr><td>Steps</td><td>{{len .Steps}}</td></tr>
+<tr><td>Tokens</td><td>{{.Usage.InputTokens}} in, {{.Usage.OutputTokens}} out</td></tr>
+</table>
+{{range .Steps}}
+<div class="step">
+<h2>Step {{.Step.Step}}: {{if .Action}}{{.Action.Type}}{{with .Detail}} <span class="detail">{{.}}</span>{{end}}{{else}}no action{{end}}{{if .Source}} <span class="detail">(by {{.Source}})</span>{{end}}</h2>
+<div class="meta">
+{{.Time.Format "15:04:05"}} &middot; {{ms .DurationMs}} &middot;
+{{len .Calls}} model call{{if ne (len .Calls) 1}}s{{end}} &middot;
+{{.Usage.InputTokens}} in / {{.Usage.OutputTokens}} out tokens
+</div>
+{{- if terminal .Action}}
+<p class="{{if eq .Action.Type "done"}}ok{{else}}error{{end}}">{{if .Action.Summary}}{{.Action.Summary}}{{else}}{{.Action.Reason}}{{end}}</p>
+{{- else if .Result}}
+<p>{{if .Result.Success}}<span class="ok">&#10003; OK</span>{{else}}<span class="error">&#10007; {{.Result.Error}}</span>{{end}}{{if .Observation}} <span class="meta">({{.Observation}})</span>{{end}}</p>
+{{- end}}
+{{- with .Verdict}}
+<p>Verifier: <span class="{{if eq .Type "approve"}}ok{{else}}error{{end}}">{{.Type}}</span>{{with verdict .}} {{.}}{{end}}</p>
+{{- end}}
+{{- if .Error}}
+<p class="error">{{.Error}}</p>
+{{- end}}
+{{- if .Image}}
+<div class="shot">
+<img src="{{.Image}}" alt="Screenshot for step {{.Step.Step}}">
+{{- with .Marker}}
+{{- if .Width}}
+<div class="area" style="left: {{pct .Left}}; top: {{pct .Top}}; width: {{pct .Width}}; height: {{pct .Height}}"></div>
+{{- else}}
+<div class="point" style="left: {{pct .Left}}; top: {{pct .Top}}"></div>
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+{{- if .Calls}}
+<details><summary>Model calls</summary>
+{{- range .Calls}}
+<pre>{{if .Source}}({{.Source}})
+{{end}}{{range .Prompt}}[{{.Role}}] {{.Text}}
+{{end}}
+&rarr; {{if .Error}}error: {{.Error}}{{else}}{{.Response}}{{end}} ({{ms .DurationMs}})</pre>
+{{- end}}
+</details>
+{{- end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,145 @@
+package session
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/capture"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// recordReportFixture records a session on 200x100 screenshots with a
+// 100px grid: a click, a click on a cell, a zoom, a rejected key press and
+// an approved done.
+func recordReportFixture(t *testing.T) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.RGBA{A: 255})
+	encoded, err := capture.Encode(img, config.FormatPNG, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	info := Info{Goal: "Open <Settings>", Provider: "anthropic", Model: "test-model", Started: started, GridCellSize: 100}
+	rec, err := NewRecorder(t.TempDir(), info, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []Step{
+		{
+			Action: &protocol.Action{Type: protocol.ActionClick, X: 50, Y: 25, Button: "left"},
+			Result: &action.Result{Success: true},
+			Calls:  []Call{{Response: "click", DurationMs: 1200}},
+		},
+		{
+			Action:      &protocol.Action{Type: protocol.ActionClick, Cell: "B1", Button: "right"},
+			Result:      &action.Result{Success: true},
+			Observation: "no visible change",
+		},
+		{
+			Action: &protocol.Action{Type: protocol.ActionZoom, X: 20, Y: 10, Width: 100, Height: 50},
+			Result: &action.Result{Success: true},
+		},
+		{
+			Action:  &protocol.Action{Type: protocol.ActionKey, Key: "enter"},
+			Result:  &action.Result{Success: true},
+			Verdict: &protocol.Verdict{Type: protocol.VerdictReject, Issues: []string{"wrong dialog"}},
+		},
+		{
+			Action:  &protocol.Action{Type: protocol.ActionDone, Summary: "settings open"},
+			Verdict: &protocol.Verdict{Type: protocol.VerdictApprove, Summary: "settings shown"},
+		},
+	}
+	for i := range steps {
+		step := &steps[i]
+		step.Step = i + 1
+		step.Time = started.Add(time.Duration(i) * time.Second)
+		if step.Screenshot, err = rec.SaveScreenshot(step.Step, encoded.MediaType, encoded.Data); err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.WriteStep(step); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Finish("completed", "settings open", nil); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Dir()
+}
+
+func TestWriteReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, recordReportFixture(t)); err != nil {
+		t.Fatalf("WriteReport: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"<h1>Open &lt;Settings&gt;</h1>",
+		`<span class="state-completed">completed</span>: settings open`,
+		"<tr><td>Steps</td><td>5</td></tr>",
+		`<h2>Step 1: click <span class="detail">left at (50, 25)</span></h2>`,
+		`<h2>Step 2: click <span class="detail">right at cell B1</span></h2>`,
+		`<span class="meta">(no visible change)</span>`,
+		`<h2>Step 3: zoom <span class="detail">100x50 at (20, 10)</span></h2>`,
+		// Markers are placed in percent of the screenshot
+		`<div class="point" style="left: 25.00%; top: 25.00%"></div>`,
+		`<div class="point" style="left: 75.00%; top: 50.00%"></div>`,
+		`<div class="area" style="left: 10.00%; top: 10.00%; width: 50.00%; height: 50.00%"></div>`,
+		`Verifier: <span class="error">reject</span> wrong dialog`,
+		`Verifier: <span class="ok">approve</span> settings shown`,
+		`<p class="ok">settings open</p>`,
+		`src="data:image/png;base64,`,
+		"1.2s",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report is missing %s", want)
+		}
+	}
+	if n := strings.Count(html, `<div class="step">`); n != 5 {
+		t.Errorf("report has %d steps, want 5", n)
+	}
+	// Neither the key press nor done point anywhere
+	if n := strings.Count(html, `class="point"`) + strings.Count(html, `class="area"`); n != 3 {
+		t.Errorf("report has %d markers, want 3", n)
+	}
+}
+
+func TestMarkerFor(t *testing.T) {
+	size := image.Pt(200, 100)
+	tests := []struct {
+		name string
+		act  protocol.Action
+		grid int
+		want *marker
+	}{
+		{"click", protocol.Action{Type: protocol.ActionClick, X: 100, Y: 50}, 0, &marker{Left: 50, Top: 50}},
+		{"cell", protocol.Action{Type: protocol.ActionClick, Cell: "A1"}, 100, &marker{Left: 25, Top: 50}},
+		{"cell without grid", protocol.Action{Type: protocol.ActionClick, Cell: "A1"}, 0, nil},
+		{"cell off the grid", protocol.Action{Type: protocol.ActionClick, Cell: "Z9"}, 100, nil},
+		{"zoom", protocol.Action{Type: protocol.ActionZoom, X: 0, Y: 50, Width: 200, Height: 50}, 0, &marker{Top: 50, Width: 100, Height: 50}},
+		{"key", protocol.Action{Type: protocol.ActionKey, Key: "enter"}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := markerFor(&tt.act, size, tt.grid)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("markerFor = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := markerFor(&protocol.Action{Type: protocol.ActionClick}, image.Point{}, 0); got != nil {
+		t.Errorf("marker on an empty screenshot: %+v", got)
+	}
+}
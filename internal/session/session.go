@@ -0,0 +1,279 @@
+// Package session records agent runs to disk for debugging and replay.
+//
+// Each run gets its own directory holding:
+//
+//	session.json       goal, timing and outcome of the run
+//	system_prompt.txt  the system prompt sent with every request
+//	steps.jsonl        one Step per line
+//	step-001.jpg ...   the screenshot the model saw at each step
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// File names inside a session directory.
+const (
+	InfoFile   = "session.json"
+	StepsFile  = "steps.jsonl"
+	SystemFile = "system_prompt.txt"
+)
+
+// SourceVerifier marks a step whose action a verifier proposed as a fix,
+// and a model call made by the verifier.
+const SourceVerifier = "verifier"
+
+// Info describes a recorded run.
+type Info struct {
+	Goal     string    `json:"goal"`
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Started  time.Time `json:"started"`
+
+	// Capture settings, so a replay sees the screen the same way
+	Display      int `json:"display,omitempty"`
+	MaxEdge      int `json:"max_edge,omitempty"`
+	GridCellSize int `json:"grid_cell_size,omitempty"` // 0 when the grid overlay was off
+
+	Finished time.Time `json:"finished"`
+	State    string    `json:"state,omitempty"`  // final agent state
+	Result   string    `json:"result,omitempty"` // done summary or failure reason
+	Error    string    `json:"error,omitempty"`  // error that ended the run
+}
+
+// Step is one agent iteration: the screenshot, every model call made for
+// it, the action chosen and what happened when it was executed.
+type Step struct {
+	Step        int               `json:"step"`
+	Time        time.Time         `json:"time"`
+	Source      string            `json:"source,omitempty"`     // SourceVerifier for remediation, empty for the agent
+	Screenshot  string            `json:"screenshot,omitempty"` // file name in the session directory
+	Screen      string            `json:"screen,omitempty"`     // screenshot description given to the model
+	Calls       []Call            `json:"calls,omitempty"`
+	Action      *protocol.Action  `json:"action,omitempty"`
+	Result      *action.Result    `json:"result,omitempty"`
+	Observation string            `json:"observation,omitempty"`
+	Verdict     *protocol.Verdict `json:"verdict,omitempty"` // verifier's review of the step
+	Error       string            `json:"error,omitempty"`
+	DurationMs  int64             `json:"duration_ms"`
+}
+
+// Call is one request to the model and its raw response.
+type Call struct {
+	Prompt     []PromptMessage `json:"prompt"` // messages added since the previous call
+	Response   string          `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Usage      llm.Usage       `json:"usage,omitzero"`
+	DurationMs int64           `json:"duration_ms"`
+	Source     string          `json:"source,omitempty"` // SourceVerifier for the verifier's calls
+}
+
+// PromptMessage is a message as sent to the model, rendered as text with
+// images replaced by a placeholder.
+type PromptMessage struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// NewCall records an exchange with the model.
+func NewCall(ex llm.Exchange) Call {
+	call := Call{
+		Response:   llm.RawResponse(ex.Reply),
+		Usage:      ex.Reply.Usage,
+		DurationMs: ex.Duration.Milliseconds(),
+	}
+	if ex.Err != nil {
+		call.Error = ex.Err.Error()
+	}
+	for _, msg := range ex.Messages {
+		call.Prompt = append(call.Prompt, PromptMessage{Role: string(msg.Role), Text: renderBlocks(msg.Content)})
+	}
+	return call
+}
+
+// renderBlocks flattens message content to text.
+func renderBlocks(blocks []llm.Block) string {
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.Type {
+		case llm.BlockText:
+			parts = append(parts, block.Text)
+		case llm.BlockImage:
+			parts = append(parts, "[screenshot]")
+		case llm.BlockToolUse:
+			parts = append(parts, fmt.Sprintf("[tool_use %s] %s %s", block.ToolID, block.ToolName, block.ToolInput))
+		case llm.BlockToolResult:
+			status := "tool_result"
+			if block.IsError {
+				status = "tool_result error"
+			}
+			parts = append(parts, fmt.Sprintf("[%s %s] %s", status, block.ToolID, block.Text))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Recorder writes a session directory as a run progresses.
+type Recorder struct {
+	mu    sync.Mutex
+	dir   string
+	info  Info
+	steps *os.File
+}
+
+// NewRecorder creates a new session directory under baseDir, named after
+// the current time, and writes the session info and system prompt.
+func NewRecorder(baseDir string, info Info, systemPrompt string) (*Recorder, error) {
+	if info.Started.IsZero() {
+		info.Started = time.Now()
+	}
+
+	dir := filepath.Join(baseDir, info.Started.Format("20060102-150405"))
+	for i := 2; ; i++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+		dir = filepath.Join(baseDir, fmt.Sprintf("%s-%d", info.Started.Format("20060102-150405"), i))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, SystemFile), []byte(systemPrompt), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write system prompt: %w", err)
+	}
+
+	steps, err := os.Create(filepath.Join(dir, StepsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create steps file: %w", err)
+	}
+
+	r := &Recorder{dir: dir, info: info, steps: steps}
+	if err := r.writeInfo(); err != nil {
+		steps.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Dir returns the session directory.
+func (r *Recorder) Dir() string {
+	return r.dir
+}
+
+// SaveScreenshot writes a base64 encoded screenshot for a step and returns
+// its file name.
+func (r *Recorder) SaveScreenshot(step int, mediaType, data string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	ext := ".jpg"
+	if mediaType == "image/png" {
+		ext = ".png"
+	}
+	name := fmt.Sprintf("step-%03d%s", step, ext)
+	if err := os.WriteFile(filepath.Join(r.dir, name), raw, 0644); err != nil {
+		return "", fmt.Errorf("failed to write screenshot: %w", err)
+	}
+	return name, nil
+}
+
+// WriteStep appends a step to the steps file.
+func (r *Recorder) WriteStep(step *Step) error {
+	data, err := json.Marshal(step)
+	if err != nil {
+		return fmt.Errorf("failed to encode step: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.steps.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write step: %w", err)
+	}
+	return nil
+}
+
+// Finish records the outcome of the run and closes the steps file.
+func (r *Recorder) Finish(state, result string, runErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.info.Finished = time.Now()
+	r.info.State = state
+	r.info.Result = result
+	if runErr != nil {
+		r.info.Error = runErr.Error()
+	}
+
+	closeErr := r.steps.Close()
+	if err := r.writeInfo(); err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to close steps file: %w", closeErr)
+	}
+	return nil
+}
+
+func (r *Recorder) writeInfo() error {
+	data, err := json.MarshalIndent(r.info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, InfoFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write session info: %w", err)
+	}
+	return nil
+}
+
+// Load reads a recorded session.
+func Load(dir string) (*Info, []Step, error) {
+	data, err := os.ReadFile(filepath.Join(dir, InfoFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", InfoFile, err)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, StepsFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read steps: %w", err)
+	}
+	var steps []Step
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var step Step
+		if err := json.Unmarshal([]byte(line), &step); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s line %d: %w", StepsFile, i+1, err)
+		}
+		steps = append(steps, step)
+	}
+	return &info, steps, nil
+}
+
+// DefaultDir returns the default directory for recorded sessions.
+func DefaultDir() (string, error) {
+	dir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
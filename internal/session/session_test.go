@@ -0,0 +1,145 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/llm"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	base := t.TempDir()
+	started := time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)
+	info := Info{Goal: "save the file", Provider: "script", Display: 2, MaxEdge: 1024, GridCellSize: 100, Started: started}
+
+	rec, err := NewRecorder(base, info, "the system prompt")
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	if want := filepath.Join(base, "20250102-150405"); rec.Dir() != want {
+		t.Errorf("Dir() = %s, want %s", rec.Dir(), want)
+	}
+
+	// "AAAA" is three zero bytes
+	shot, err := rec.SaveScreenshot(1, "image/png", "AAAA")
+	if err != nil {
+		t.Fatalf("SaveScreenshot: %v", err)
+	}
+	if shot != "step-001.png" {
+		t.Errorf("screenshot saved as %s", shot)
+	}
+
+	steps := []Step{
+		{
+			Step:       1,
+			Time:       started.Add(time.Second),
+			Screenshot: shot,
+			Screen:     "Display 2 of 2",
+			Calls: []Call{{
+				Prompt:     []PromptMessage{{Role: "user", Text: "[screenshot]"}},
+				Response:   `[tool_use call-1] key {"key":"ctrl+s"}`,
+				Usage:      llm.Usage{InputTokens: 1200, OutputTokens: 30},
+				DurationMs: 800,
+			}},
+			Action:      &protocol.Action{Type: protocol.ActionKey, Key: "ctrl+s"},
+			Result:      &action.Result{Success: true},
+			Observation: "region 0,0-10,10 changed; <1% of screen",
+			DurationMs:  1500,
+		},
+		{
+			Step:   2,
+			Time:   started.Add(3 * time.Second),
+			Source: SourceVerifier,
+			Action: &protocol.Action{Type: protocol.ActionFileWrite, Path: "/tmp/a.txt", Content: "x"},
+			Result: &action.Result{Error: "rejected by operator"},
+		},
+		{
+			Step:    3,
+			Time:    started.Add(4 * time.Second),
+			Action:  &protocol.Action{Type: protocol.ActionDone, Summary: "saved"},
+			Verdict: &protocol.Verdict{Type: protocol.VerdictApprove, Summary: "looks right"},
+		},
+	}
+	for i := range steps {
+		if err := rec.WriteStep(&steps[i]); err != nil {
+			t.Fatalf("WriteStep: %v", err)
+		}
+	}
+	if err := rec.Finish("failed", "", errors.New("stopped")); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	gotInfo, gotSteps, err := Load(rec.Dir())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if gotInfo.Goal != info.Goal || gotInfo.Display != 2 || gotInfo.MaxEdge != 1024 || gotInfo.GridCellSize != 100 {
+		t.Errorf("info = %+v", gotInfo)
+	}
+	if !gotInfo.Started.Equal(started) || gotInfo.Finished.IsZero() {
+		t.Errorf("run timed %v to %v", gotInfo.Started, gotInfo.Finished)
+	}
+	if gotInfo.State != "failed" || gotInfo.Error != "stopped" {
+		t.Errorf("outcome = %q, %q", gotInfo.State, gotInfo.Error)
+	}
+
+	// Times come back in UTC or local depending on the encoding, so compare them apart
+	for i := range gotSteps {
+		if !gotSteps[i].Time.Equal(steps[i].Time) {
+			t.Errorf("step %d time = %v, want %v", i+1, gotSteps[i].Time, steps[i].Time)
+		}
+		gotSteps[i].Time = steps[i].Time
+	}
+	if !reflect.DeepEqual(gotSteps, steps) {
+		t.Errorf("steps = %+v\nwant %+v", gotSteps, steps)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(rec.Dir(), SystemFile)); err != nil || string(data) != "the system prompt" {
+		t.Errorf("system prompt = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(rec.Dir(), shot)); err != nil || len(data) != 3 {
+		t.Errorf("screenshot = %v, %v", data, err)
+	}
+}
+
+func TestRecorderSameSecond(t *testing.T) {
+	base := t.TempDir()
+	info := Info{Goal: "twice", Started: time.Date(2025, 1, 2, 15, 4, 5, 0, time.Local)}
+
+	first, err := NewRecorder(base, info, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewRecorder(base, info, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Dir() != first.Dir()+"-2" {
+		t.Errorf("second session in %s, want %s-2", second.Dir(), first.Dir())
+	}
+}
+
+func TestLoadRejectsBadSteps(t *testing.T) {
+	rec, err := NewRecorder(t.TempDir(), Info{Goal: "broken"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Finish("stopped", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rec.Dir(), StepsFile), []byte("{\"step\": 1}\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Load(rec.Dir()); err == nil {
+		t.Error("Load accepted a corrupt steps file")
+	}
+	if _, _, err := Load(t.TempDir()); err == nil {
+		t.Error("Load accepted a directory without a session")
+	}
+}
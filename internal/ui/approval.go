@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/thesimpledev/golemming/internal/agent"
+	"github.com/thesimpledev/golemming/pkg/protocol"
+)
+
+// approvalMode is what the operator is doing with a pending approval.
+type approvalMode int
+
+const (
+	approvalChoosing  approvalMode = iota // deciding between approve, reject and edit
+	approvalRejecting                     // typing feedback for the model
+	approvalEditing                       // editing the action as JSON
+)
+
+// approvalPrompt is an action waiting for the operator in the running view.
+type approvalPrompt struct {
+	req   agent.ApprovalRequest
+	reply chan<- agent.Decision
+	mode  approvalMode
+	err   string
+}
+
+// newApprovalInput creates the text input used for feedback and edits.
+func newApprovalInput() textinput.Model {
+	in := textinput.New()
+	in.Width = 80
+	in.CharLimit = 0
+	return in
+}
+
+// handleApprovalKey handles keys while an action waits for approval.
+// handled is false for keys left to the normal running view handling.
+func (m Model) handleApprovalKey(msg tea.KeyMsg) (model tea.Model, cmd tea.Cmd, handled bool) {
+	p := m.approval
+	key := msg.String()
+	if key == "ctrl+c" {
+		return m, nil, false
+	}
+
+	if p.mode == approvalChoosing {
+		switch key {
+		case "y", "enter":
+			return m.decide(agent.Decision{Approved: true}), nil, true
+		case "n":
+			p.mode = approvalRejecting
+			m.approvalInput.SetValue("")
+			m.approvalInput.Placeholder = "Why? (optional, passed on to the model)"
+			return m, m.approvalInput.Focus(), true
+		case "e":
+			data, _ := json.Marshal(p.req.Action)
+			p.mode = approvalEditing
+			m.approvalInput.SetValue(string(data))
+			m.approvalInput.Placeholder = ""
+			m.approvalInput.CursorEnd()
+			return m, m.approvalInput.Focus(), true
+		}
+		return m, nil, false
+	}
+
+	switch key {
+	case "esc":
+		p.mode = approvalChoosing
+		p.err = ""
+		m.approvalInput.Blur()
+		return m, nil, true
+	case "enter":
+		value := strings.TrimSpace(m.approvalInput.Value())
+		if p.mode == approvalRejecting {
+			return m.decide(agent.Decision{Feedback: value}), nil, true
+		}
+		edited, err := agent.EditAction(p.req.Action, value)
+		if err != nil {
+			p.err = err.Error()
+			return m, nil, true
+		}
+		return m.decide(agent.Decision{Approved: true, Action: edited}), nil, true
+	}
+
+	m.approvalInput, cmd = m.approvalInput.Update(msg)
+	return m, cmd, true
+}
+
+// decide answers the pending approval and returns to the running view.
+func (m Model) decide(decision agent.Decision) Model {
+	m.approval.reply <- decision
+	m.approval = nil
+	m.approvalInput.Blur()
+	return m
+}
+
+// viewApproval renders the action waiting for approval.
+func (m Model) viewApproval() string {
+	p := m.approval
+	act := p.req.Action
+
+	var b strings.Builder
+	b.WriteString(WarningStyle.Render("Approval needed"))
+	b.WriteString("\n\n")
+	b.WriteString(ActionTypeStyle.Render(string(act.Type)))
+	b.WriteString(" ")
+	b.WriteString(m.formatActionDetail(act))
+	b.WriteString("\n")
+
+	if p.req.HasTarget {
+		b.WriteString(MutedStyle.Render(fmt.Sprintf("Click point: (%d, %d) in the screenshot, (%d, %d) on screen",
+			p.req.Target.X, p.req.Target.Y, p.req.Screen.X, p.req.Screen.Y)))
+		b.WriteString("\n")
+	}
+	switch act.Type {
+	case protocol.ActionType_:
+		b.WriteString(MutedStyle.Render("Text: "))
+		b.WriteString(act.Text)
+		b.WriteString("\n")
+	case protocol.ActionFileWrite:
+		b.WriteString(MutedStyle.Render(fmt.Sprintf("Path: %s (%d bytes)", act.Path, len(act.Content))))
+		b.WriteString("\n")
+		lines := strings.Split(act.Content, "\n")
+		for i, line := range lines {
+			if i == 5 {
+				b.WriteString(DimStyle.Render(fmt.Sprintf("  ... %d more lines", len(lines)-5)))
+				b.WriteString("\n")
+				break
+			}
+			b.WriteString(DimStyle.Render("  " + line))
+			b.WriteString("\n")
+		}
+	}
+
+	switch p.mode {
+	case approvalChoosing:
+		b.WriteString(HelpStyle.Render("y/Enter approve • n reject with feedback • e edit • Esc stop agent"))
+	case approvalRejecting, approvalEditing:
+		label := "Feedback: "
+		if p.mode == approvalEditing {
+			label = "Action: "
+		}
+		b.WriteString("\n")
+		b.WriteString(PromptStyle.Render(label))
+		b.WriteString(m.approvalInput.View())
+		b.WriteString("\n")
+		if p.err != "" {
+			b.WriteString(ErrorStyle.Render(p.err))
+			b.WriteString("\n")
+		}
+		b.WriteString(HelpStyle.Render("Enter to submit • Esc to go back"))
+	}
+
+	return FocusedBoxStyle.Render(b.String())
+}
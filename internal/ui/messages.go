@@ -2,6 +2,8 @@ package ui
 
 import (
 	"github.com/thesimpledev/golemming/internal/action"
+	"github.com/thesimpledev/golemming/internal/agent"
+	"github.com/thesimpledev/golemming/internal/llm"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
@@ -11,6 +13,23 @@ type ActionExecutedMsg struct {
 	Result *action.Result
 }
 
+// RetryMsg is sent when a failed LLM call is about to be retried.
+type RetryMsg struct {
+	Event llm.RetryEvent
+}
+
+// VerdictMsg is sent when the verifier reviews the agent's work.
+type VerdictMsg struct {
+	Verdict *protocol.Verdict
+}
+
+// ApprovalMsg is sent when an action waits for the operator's approval.
+// The decision is sent on Reply.
+type ApprovalMsg struct {
+	Request agent.ApprovalRequest
+	Reply   chan<- agent.Decision
+}
+
 // AgentDoneMsg is sent when the agent completes.
 type AgentDoneMsg struct {
 	Success bool
@@ -23,7 +42,9 @@ type AgentErrorMsg struct {
 }
 
 // ScreenshotMsg is sent when a screenshot is captured.
-type ScreenshotMsg struct{}
+type ScreenshotMsg struct {
+	Info agent.ScreenshotInfo
+}
 
 // ConfigSavedMsg is sent when configuration is saved.
 type ConfigSavedMsg struct{}
@@ -3,7 +3,6 @@ package ui
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -14,6 +13,8 @@ import (
 	"github.com/thesimpledev/golemming/internal/action"
 	"github.com/thesimpledev/golemming/internal/agent"
 	"github.com/thesimpledev/golemming/internal/config"
+	"github.com/thesimpledev/golemming/internal/input"
+	"github.com/thesimpledev/golemming/internal/llm"
 	"github.com/thesimpledev/golemming/pkg/protocol"
 )
 
@@ -42,6 +43,8 @@ type Model struct {
 	height       int
 	config       *config.Config
 	configLoaded bool
+	overrides    func(*config.Config)
+	input        input.Backend
 
 	// Setup view
 	apiKeyInput textinput.Model
@@ -58,6 +61,14 @@ type Model struct {
 	actionHistory []HistoryItem
 	spinner       spinner.Model
 	iterationNum  int
+	retry         *llm.RetryEvent // pending retry, cleared by the next action
+	screenshot    *agent.ScreenshotInfo
+	verdict       *protocol.Verdict // latest verifier verdict
+	approval      *approvalPrompt   // action waiting for approval, if any
+	approvalInput textinput.Model
+	noteInput     textinput.Model
+	noting        bool     // typing a note for the agent
+	notes         []string // notes sent during this run
 	updateCh      chan tea.Msg
 
 	// Complete view
@@ -68,8 +79,10 @@ type Model struct {
 	prevView View
 }
 
-// New creates a new Model.
-func New() Model {
+// New creates a new Model whose agents send input through backend.
+// overrides, if non-nil, is applied to the loaded configuration (e.g. for
+// command line flags) but never saved.
+func New(backend input.Backend, overrides func(*config.Config)) Model {
 	// API key input
 	apiInput := textinput.New()
 	apiInput.Placeholder = "sk-ant-..."
@@ -91,16 +104,24 @@ func New() Model {
 
 	m := Model{
 		view:        ViewSetup,
+		input:       backend,
+		overrides:   overrides,
 		apiKeyInput: apiInput,
 		goalInput:   goalInput,
 		spinner:     s,
 		goalHistory: make([]string, 0),
 		updateCh:    make(chan tea.Msg, 100),
+
+		approvalInput: newApprovalInput(),
+		noteInput:     newNoteInput(),
 	}
 
 	// Try to load existing config
-	cfg, err := config.Load()
-	if err == nil {
+	cfg := config.Read()
+	if overrides != nil {
+		overrides(cfg)
+	}
+	if err := cfg.Validate(); err == nil {
 		m.config = cfg
 		m.configLoaded = true
 		m.view = ViewInput
@@ -148,9 +169,26 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			Result:    msg.Result,
 		})
 		m.iterationNum++
+		m.retry = nil
 		// Continue listening for more updates
 		return m, m.waitForUpdate
 
+	case RetryMsg:
+		m.retry = &msg.Event
+		return m, m.waitForUpdate
+
+	case ScreenshotMsg:
+		m.screenshot = &msg.Info
+		return m, m.waitForUpdate
+
+	case VerdictMsg:
+		m.verdict = msg.Verdict
+		return m, m.waitForUpdate
+
+	case ApprovalMsg:
+		m.approval = &approvalPrompt{req: msg.Request, reply: msg.Reply}
+		return m, m.waitForUpdate
+
 	case AgentDoneMsg:
 		m.view = ViewComplete
 		if msg.Success {
@@ -175,12 +213,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.apiKeyInput, cmd = m.apiKeyInput.Update(msg)
 	case ViewInput:
 		m.goalInput, cmd = m.goalInput.Update(msg)
+	case ViewRunning:
+		if m.approval != nil {
+			m.approvalInput, cmd = m.approvalInput.Update(msg)
+		} else if m.noting {
+			m.noteInput, cmd = m.noteInput.Update(msg)
+		}
 	}
 
 	return m, cmd
 }
 
 func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.view == ViewRunning && m.approval != nil {
+		if model, cmd, handled := m.handleApprovalKey(msg); handled {
+			return model, cmd
+		}
+	}
+	if m.view == ViewRunning && m.noting {
+		if model, cmd, handled := m.handleNoteKey(msg); handled {
+			return model, cmd
+		}
+	}
+
 	switch msg.String() {
 	case "ctrl+c":
 		if m.view == ViewRunning && m.agentCancel != nil {
@@ -192,6 +247,32 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, tea.Quit
 
+	case "p":
+		if m.view == ViewRunning && m.agent != nil {
+			if m.agent.State() == agent.StatePaused {
+				m.agent.Resume()
+				return m, nil
+			}
+			m.agent.Pause()
+			// Pausing cancels a pending approval; it is asked again on resume
+			m.approval = nil
+			m.approvalInput.Blur()
+			return m, nil
+		}
+
+	case "s":
+		if m.view == ViewRunning && m.agent != nil {
+			m.agent.Step()
+			return m, nil
+		}
+
+	case "i":
+		if m.view == ViewRunning && m.agent != nil && m.approval == nil {
+			m.noting = true
+			m.noteInput.SetValue("")
+			return m, m.noteInput.Focus()
+		}
+
 	case "?":
 		// Show help (except when typing in input)
 		if m.view == ViewInput && m.goalInput.Value() == "" {
@@ -220,6 +301,12 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.view = ViewInput
 			m.actionHistory = nil
 			m.iterationNum = 0
+			m.retry = nil
+			m.screenshot = nil
+			m.verdict = nil
+			m.approval = nil
+			m.noting = false
+			m.notes = nil
 			m.goalInput.SetValue("")
 			m.goalInput.Focus()
 			return m, nil
@@ -268,6 +355,9 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 			m.setupError = "Failed to save config: " + err.Error()
 			return m, nil
 		}
+		if m.overrides != nil {
+			m.overrides(m.config)
+		}
 
 		m.configLoaded = true
 		m.view = ViewInput
@@ -286,6 +376,12 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		m.currentGoal = goal
 		m.actionHistory = nil
 		m.iterationNum = 0
+		m.retry = nil
+		m.screenshot = nil
+		m.verdict = nil
+		m.approval = nil
+		m.noting = false
+		m.notes = nil
 
 		// Start agent
 		m.view = ViewRunning
@@ -298,6 +394,12 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		m.view = ViewInput
 		m.actionHistory = nil
 		m.iterationNum = 0
+		m.retry = nil
+		m.screenshot = nil
+		m.verdict = nil
+		m.approval = nil
+		m.noting = false
+		m.notes = nil
 		m.goalInput.SetValue("")
 		m.goalInput.Focus()
 		return m, nil
@@ -310,7 +412,11 @@ func (m *Model) startAgent(goal string) tea.Cmd {
 	ctx, cancel := context.WithCancel(context.Background())
 	m.agentCancel = cancel
 
-	ag := agent.New(m.config)
+	ag, err := agent.New(m.config, m.input)
+	if err != nil {
+		cancel()
+		return func() tea.Msg { return AgentErrorMsg{Error: err} }
+	}
 	m.agent = ag
 
 	updateCh := m.updateCh
@@ -322,6 +428,38 @@ func (m *Model) startAgent(goal string) tea.Cmd {
 		default:
 		}
 	})
+	ag.OnRetry(func(event llm.RetryEvent) {
+		select {
+		case updateCh <- RetryMsg{Event: event}:
+		default:
+		}
+	})
+	ag.OnScreenshot(func(info agent.ScreenshotInfo) {
+		select {
+		case updateCh <- ScreenshotMsg{Info: info}:
+		default:
+		}
+	})
+	ag.OnApproval(func(ctx context.Context, req agent.ApprovalRequest) (agent.Decision, error) {
+		reply := make(chan agent.Decision, 1)
+		select {
+		case updateCh <- ApprovalMsg{Request: req, Reply: reply}:
+		case <-ctx.Done():
+			return agent.Decision{}, ctx.Err()
+		}
+		select {
+		case decision := <-reply:
+			return decision, nil
+		case <-ctx.Done():
+			return agent.Decision{}, ctx.Err()
+		}
+	})
+	ag.OnVerdict(func(verdict *protocol.Verdict) {
+		select {
+		case updateCh <- VerdictMsg{Verdict: verdict}:
+		default:
+		}
+	})
 
 	// Run agent in goroutine
 	go func() {
@@ -450,8 +588,22 @@ func (m Model) viewRunning() string {
 	// Header
 	b.WriteString(m.spinner.View())
 	b.WriteString(" ")
-	b.WriteString(StatusRunning.Render("Running"))
+	paused := m.agent != nil && m.agent.State() == agent.StatePaused
+	switch {
+	case paused:
+		b.WriteString(WarningStyle.Render("Paused"))
+	case m.approval != nil:
+		b.WriteString(WarningStyle.Render("Waiting for approval"))
+	default:
+		b.WriteString(StatusRunning.Render("Running"))
+	}
 	b.WriteString(MutedStyle.Render(fmt.Sprintf(" • Iteration %d", m.iterationNum)))
+	if m.screenshot != nil {
+		b.WriteString(MutedStyle.Render(" • " + formatScreenshot(*m.screenshot)))
+	}
+	if m.retry != nil {
+		b.WriteString(WarningStyle.Render(fmt.Sprintf(" • retrying (%d/%d)", m.retry.Retry, m.retry.MaxRetries)))
+	}
 	b.WriteString("\n\n")
 
 	// Goal
@@ -459,6 +611,27 @@ func (m Model) viewRunning() string {
 	b.WriteString(m.currentGoal)
 	b.WriteString("\n\n")
 
+	if m.approval != nil {
+		b.WriteString(m.viewApproval())
+		b.WriteString("\n\n")
+	}
+
+	if len(m.notes) > 0 {
+		b.WriteString(MutedStyle.Render("Notes:"))
+		b.WriteString("\n")
+		for _, note := range m.notes {
+			b.WriteString(DimStyle.Render("  " + note))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if m.verdict != nil {
+		b.WriteString(MutedStyle.Render("Verifier: "))
+		b.WriteString(renderVerdict(m.verdict))
+		b.WriteString("\n\n")
+	}
+
 	// Action history
 	if len(m.actionHistory) > 0 {
 		b.WriteString(MutedStyle.Render("Actions:"))
@@ -478,7 +651,17 @@ func (m Model) viewRunning() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(HelpStyle.Render("Press Esc or Ctrl+C to stop"))
+	switch {
+	case m.noting:
+		b.WriteString(PromptStyle.Render("Note: "))
+		b.WriteString(m.noteInput.View())
+		b.WriteString("\n")
+		b.WriteString(HelpStyle.Render("Enter to send to the agent • Esc to cancel"))
+	case paused:
+		b.WriteString(HelpStyle.Render("p to resume • s to step once • i to add a note • Esc or Ctrl+C to stop"))
+	default:
+		b.WriteString(HelpStyle.Render("p to pause • i to add a note • Esc or Ctrl+C to stop"))
+	}
 
 	return b.String()
 }
@@ -547,35 +730,11 @@ func (m Model) formatActionLine(item HistoryItem) string {
 }
 
 func (m Model) formatActionDetail(act *protocol.Action) string {
-	switch act.Type {
-	case protocol.ActionClick:
-		btn := act.Button
-		if btn == "" {
-			btn = "left"
-		}
-		if act.Double {
-			return ActionDetailStyle.Render(fmt.Sprintf("double %s at (%d, %d)", btn, act.X, act.Y))
-		}
-		return ActionDetailStyle.Render(fmt.Sprintf("%s at (%d, %d)", btn, act.X, act.Y))
-	case protocol.ActionType_:
-		text := act.Text
-		if len(text) > 30 {
-			text = text[:30] + "..."
-		}
-		return ActionDetailStyle.Render(fmt.Sprintf("%q", text))
-	case protocol.ActionKey:
-		return ActionDetailStyle.Render(act.Key)
-	case protocol.ActionScroll:
-		return ActionDetailStyle.Render(fmt.Sprintf("%s %d", act.Direction, act.Amount))
-	case protocol.ActionFileRead:
-		return ActionDetailStyle.Render(filepath.Base(act.Path))
-	case protocol.ActionFileWrite:
-		return ActionDetailStyle.Render(filepath.Base(act.Path))
-	case protocol.ActionWait:
-		return ActionDetailStyle.Render(fmt.Sprintf("%dms", act.Ms))
-	default:
+	detail := action.Describe(act)
+	if detail == "" {
 		return ""
 	}
+	return ActionDetailStyle.Render(detail)
 }
 
 func (m Model) viewHelp() string {
@@ -595,6 +754,10 @@ func (m Model) viewHelp() string {
 		{"Esc", "Stop agent / Go back / New goal"},
 		{"Ctrl+C", "Stop agent / Quit application"},
 		{"?", "Show this help screen"},
+		{"p", "Pause / resume a running agent"},
+		{"s", "Run one step of a paused agent"},
+		{"i", "Send a note to a running agent"},
+		{"y / n / e", "Approve / reject / edit an action waiting for approval"},
 	}
 
 	for _, s := range shortcuts {
@@ -640,9 +803,39 @@ func (m Model) viewHelp() string {
 	b.WriteString("\n")
 	b.WriteString("    golemming -goal \"Open Calculator\"\n")
 	b.WriteString("    golemming -goal \"...\" -max-iterations 50\n")
+	b.WriteString("    golemming -goal \"...\" -script actions.json\n")
+	b.WriteString("    golemming -goal \"...\" -verify post_task\n")
+	b.WriteString("    golemming -goal \"...\" -approve file_write\n\n")
+
+	b.WriteString(DimStyle.Render("  Replay a recorded session (no LLM):"))
+	b.WriteString("\n")
+	b.WriteString("    golemming replay ~/.golemming/sessions/20250101-120000\n")
+	b.WriteString("    golemming replay -compare -delay 1s <session-dir>\n")
+
+	b.WriteString(DimStyle.Render("  HTML report of a recorded session:"))
+	b.WriteString("\n")
+	b.WriteString("    golemming report <session-dir>\n")
 
 	b.WriteString("\n")
 	b.WriteString(HelpStyle.Render("Press Enter or Esc to go back"))
 
 	return b.String()
 }
+
+// renderVerdict styles a verdict type, followed by action.DescribeVerdict.
+func renderVerdict(verdict *protocol.Verdict) string {
+	style := SuccessStyle
+	switch verdict.Type {
+	case protocol.VerdictReject:
+		style = ErrorStyle
+	case protocol.VerdictRemediate:
+		style = WarningStyle
+	}
+	return style.Render(string(verdict.Type)) + " " + action.DescribeVerdict(verdict)
+}
+
+// formatScreenshot summarizes a screenshot as dimensions, format and size.
+func formatScreenshot(info agent.ScreenshotInfo) string {
+	format := strings.TrimPrefix(info.MediaType, "image/")
+	return fmt.Sprintf("%dx%d %s %.1f KB", info.Width, info.Height, format, float64(info.Size)/1024)
+}
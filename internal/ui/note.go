@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newNoteInput creates the text input for operator notes.
+func newNoteInput() textinput.Model {
+	in := textinput.New()
+	in.Placeholder = "Guidance for the agent's next step"
+	in.Width = 80
+	in.CharLimit = 500
+	return in
+}
+
+// handleNoteKey handles keys while the operator types a note. handled is
+// false for keys left to the normal running view handling.
+func (m Model) handleNoteKey(msg tea.KeyMsg) (model tea.Model, cmd tea.Cmd, handled bool) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, nil, false
+	case "esc":
+		m.noting = false
+		m.noteInput.Blur()
+		return m, nil, true
+	case "enter":
+		if note := strings.TrimSpace(m.noteInput.Value()); note != "" {
+			m.agent.AddNote(note)
+			m.notes = append(m.notes, note)
+		}
+		m.noting = false
+		m.noteInput.Blur()
+		return m, nil, true
+	}
+
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd, true
+}
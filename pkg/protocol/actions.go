@@ -1,6 +1,8 @@
 // Package protocol defines the action types and structures for agent-LLM communication.
 package protocol
 
+import "encoding/json"
+
 // ActionType represents the type of action an agent can perform.
 type ActionType string
 
@@ -12,6 +14,8 @@ const (
 	ActionFileRead  ActionType = "file_read"
 	ActionFileWrite ActionType = "file_write"
 	ActionWait      ActionType = "wait"
+	ActionZoom      ActionType = "zoom"
+	ActionZoomReset ActionType = "zoom_reset"
 	ActionDone      ActionType = "done"
 	ActionFailed    ActionType = "failed"
 )
@@ -32,12 +36,57 @@ type Action struct {
 	Ms        int        `json:"ms,omitempty"`
 	Summary   string     `json:"summary,omitempty"`
 	Reason    string     `json:"reason,omitempty"`
+	Cell      string     `json:"cell,omitempty"` // grid cell to click instead of x, y
+	Width     int        `json:"width,omitempty"`
+	Height    int        `json:"height,omitempty"`
+	Display   int        `json:"display,omitempty"` // switch later screenshots to this display, -1 for all
+
+	// pointGiven is set when x or y was present in the JSON the action was
+	// decoded from, so that a click at (0, 0) can be told from a click
+	// without coordinates.
+	pointGiven bool
+}
+
+// plainAction is Action without its JSON methods.
+type plainAction Action
+
+// UnmarshalJSON decodes an action, noting whether coordinates were given.
+func (a *Action) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainAction)(a)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	_, hasX := fields["x"]
+	_, hasY := fields["y"]
+	a.pointGiven = a.pointGiven || hasX || hasY
+	return nil
+}
+
+// MarshalJSON encodes an action. A click by coordinates always carries x
+// and y, so that a click at (0, 0) decodes as one.
+func (a Action) MarshalJSON() ([]byte, error) {
+	if a.Type != ActionClick || a.Cell != "" {
+		return json.Marshal(plainAction(a))
+	}
+	return json.Marshal(struct {
+		plainAction
+		X int `json:"x"`
+		Y int `json:"y"`
+	}{plainAction(a), a.X, a.Y})
 }
 
 // Validate checks if the action has valid fields for its type.
 func (a *Action) Validate() error {
 	switch a.Type {
 	case ActionClick:
+		// Coordinates are optional in the schema so that cell can replace
+		// them; a click at the origin must have given them explicitly
+		if a.Cell == "" && a.X == 0 && a.Y == 0 && !a.pointGiven {
+			return &ValidationError{Field: "x", Message: "x and y, or cell, are required for click action"}
+		}
 		if a.Button == "" {
 			a.Button = "left"
 		}
@@ -68,6 +117,12 @@ func (a *Action) Validate() error {
 		if a.Ms == 0 {
 			a.Ms = 500
 		}
+	case ActionZoom:
+		if a.Width <= 0 || a.Height <= 0 {
+			return &ValidationError{Field: "width", Message: "width and height are required for zoom action"}
+		}
+	case ActionZoomReset:
+		// No fields
 	case ActionDone:
 		// Summary is optional
 	case ActionFailed:
@@ -77,6 +132,9 @@ func (a *Action) Validate() error {
 	default:
 		return &ValidationError{Field: "type", Message: "unknown action type: " + string(a.Type)}
 	}
+	if a.Display < -1 {
+		return &ValidationError{Field: "display", Message: "display must be a display number or -1 for all displays"}
+	}
 	return nil
 }
 
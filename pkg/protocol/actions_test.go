@@ -0,0 +1,153 @@
+package protocol
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestValidateClick(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  Action
+		wantErr bool
+	}{
+		{"coordinates", Action{Type: ActionClick, X: 100, Y: 200}, false},
+		{"one coordinate zero", Action{Type: ActionClick, X: 0, Y: 200}, false},
+		{"cell", Action{Type: ActionClick, Cell: "C4"}, false},
+		{"cell and coordinates", Action{Type: ActionClick, X: 100, Y: 200, Cell: "C4"}, false},
+		{"neither", Action{Type: ActionClick}, true},
+		{"button only", Action{Type: ActionClick, Button: "right"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act := tt.action
+			err := act.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && act.Button == "" {
+				t.Error("button was not defaulted")
+			}
+		})
+	}
+}
+
+func TestValidateClickJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"origin", `{"type": "click", "x": 0, "y": 0}`, false},
+		{"left edge", `{"type": "click", "x": 0, "y": 300}`, false},
+		{"only x", `{"type": "click", "x": 0}`, false},
+		{"cell", `{"type": "click", "cell": "A1"}`, false},
+		{"neither", `{"type": "click", "button": "right"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act Action
+			if err := json.Unmarshal([]byte(tt.json), &act); err != nil {
+				t.Fatal(err)
+			}
+			if err := act.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClickAtOriginRoundTrip(t *testing.T) {
+	var act Action
+	if err := json.Unmarshal([]byte(`{"type": "click", "x": 0, "y": 0}`), &act); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recorded sessions and scripts encode the click again
+	data, err := json.Marshal(act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Action
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Validate(); err != nil {
+		t.Errorf("click at the origin encoded as %s is invalid: %v", data, err)
+	}
+
+	// Other actions keep leaving out zero coordinates
+	data, err = json.Marshal(Action{Type: ActionKey, Key: "enter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"key","key":"enter"}` {
+		t.Errorf("key encoded as %s", data)
+	}
+}
+
+func TestClickSchemaAllowsCellOnly(t *testing.T) {
+	var click Tool
+	for _, tool := range Tools {
+		if tool.Type == ActionClick {
+			click = tool
+		}
+	}
+	schema := click.InputSchema()
+
+	if required := schema["required"].([]string); len(required) != 0 {
+		t.Errorf("click requires %v, want no required fields", required)
+	}
+	properties := schema["properties"].(map[string]any)
+	for _, name := range []string{"x", "y", "cell", "button", "double"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("click schema has no %q property", name)
+		}
+	}
+	if !slices.Contains(click.Optional, "cell") {
+		t.Error("cell is not optional")
+	}
+}
+
+func TestRemediateSchemaDescribesActions(t *testing.T) {
+	var remediate Tool
+	for _, tool := range VerdictTools {
+		if tool.Type == ActionType(VerdictRemediate) {
+			remediate = tool
+		}
+	}
+	actions := remediate.InputSchema()["properties"].(map[string]any)["actions"].(map[string]any)
+	variants := actions["items"].(map[string]any)["oneOf"].([]any)
+
+	types := make(map[string]map[string]any)
+	for _, v := range variants {
+		schema := v.(map[string]any)
+		typ := schema["properties"].(map[string]any)["type"].(map[string]any)
+		types[typ["enum"].([]string)[0]] = schema
+	}
+	if _, ok := types[string(ActionDone)]; ok {
+		t.Error("remediation can be done")
+	}
+	if len(types) != len(Tools)-2 {
+		t.Errorf("%d action variants, want %d", len(types), len(Tools)-2)
+	}
+
+	write, ok := types[string(ActionFileWrite)]
+	if !ok {
+		t.Fatal("no file_write variant")
+	}
+	if required := write["required"].([]string); !slices.Equal(required, []string{"type", "path", "content"}) {
+		t.Errorf("file_write requires %v", required)
+	}
+	if _, ok := write["properties"].(map[string]any)["content"]; !ok {
+		t.Error("file_write variant has no content property")
+	}
+
+	// Building the variants must not change the tools they come from
+	for _, tool := range Tools {
+		if slices.Contains(tool.Required, "type") {
+			t.Errorf("%s tool now requires type", tool.Type)
+		}
+	}
+}
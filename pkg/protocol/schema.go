@@ -0,0 +1,206 @@
+package protocol
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Tool describes an action type as a tool the model can call. The tool name
+// is the action type and its input is the subset of Action fields listed in
+// Required and Optional.
+type Tool struct {
+	Type        ActionType
+	Description string
+	Required    []string // JSON field names that must be present
+	Optional    []string // JSON field names that may be present
+
+	// input is the struct the tool's fields are taken from; Action if nil.
+	input any
+}
+
+// Tools lists every action the model can take, in the order they are
+// presented to the model.
+var Tools = []Tool{
+	{
+		Type:        ActionClick,
+		Description: "Click at screen coordinates taken from the current screenshot, or at the centre of a grid cell when the grid overlay is shown. Give x and y, or cell.",
+		Optional:    []string{"x", "y", "button", "double", "cell"},
+	},
+	{
+		Type:        ActionType_,
+		Description: "Type text into the focused control.",
+		Required:    []string{"text"},
+	},
+	{
+		Type:        ActionKey,
+		Description: "Press a key or key combination such as enter, ctrl+c or alt+f4.",
+		Required:    []string{"key"},
+	},
+	{
+		Type:        ActionScroll,
+		Description: "Scroll the mouse wheel at the current cursor position.",
+		Required:    []string{"direction"},
+		Optional:    []string{"amount"},
+	},
+	{
+		Type:        ActionFileRead,
+		Description: "Read a file's contents. The contents are returned with the next step.",
+		Required:    []string{"path"},
+	},
+	{
+		Type:        ActionFileWrite,
+		Description: "Write content to a file, creating parent directories as needed.",
+		Required:    []string{"path", "content"},
+	},
+	{
+		Type:        ActionWait,
+		Description: "Wait for the UI to stabilize, e.g. while a window or dialog opens, or switch the screenshot to another display.",
+		Optional:    []string{"ms", "display"},
+	},
+	{
+		Type:        ActionZoom,
+		Description: "Zoom in on a region of the current screenshot so the next screenshots show it magnified, to read small text or hit small targets.",
+		Required:    []string{"x", "y", "width", "height"},
+	},
+	{
+		Type:        ActionZoomReset,
+		Description: "Leave zoom and show the whole display again.",
+	},
+	{
+		Type:        ActionDone,
+		Description: "Report that the goal has been achieved.",
+		Optional:    []string{"summary"},
+	},
+	{
+		Type:        ActionFailed,
+		Description: "Report that the goal cannot be achieved.",
+		Required:    []string{"reason"},
+	},
+}
+
+// fieldDocs describes each Action field for the generated schemas.
+var fieldDocs = map[string]string{
+	"x":         "X coordinate in screenshot pixels",
+	"y":         "Y coordinate in screenshot pixels",
+	"button":    "Mouse button (default left)",
+	"double":    "Double-click instead of single click",
+	"text":      "Text to type",
+	"key":       "Key name or combination joined with + (e.g. enter, ctrl+shift+s)",
+	"direction": "Scroll direction",
+	"amount":    "Number of scroll units (default 3)",
+	"path":      "Absolute file path",
+	"content":   "Content to write",
+	"ms":        "Milliseconds to wait (default 500)",
+	"summary":   "What was accomplished",
+	"reason":    "Why the goal cannot be achieved",
+	"cell":      "Grid cell to click instead of x and y, e.g. C4 (only when the grid overlay is shown)",
+	"width":     "Width in screenshot pixels",
+	"height":    "Height in screenshot pixels",
+	"display":   "Display to show in the following screenshots (1-based), or -1 for all displays",
+	"issues":    "Specific problems found, one per item",
+	"actions":   "Actions that fix the issues, executed in order",
+}
+
+// fieldEnums restricts string fields to a fixed set of values.
+var fieldEnums = map[string][]string{
+	"button":    {"left", "right", "middle"},
+	"direction": {"up", "down"},
+}
+
+// InputSchema returns the JSON schema for the tool's input, generated from
+// the json tags and Go types of the Action fields the tool uses.
+func (t Tool) InputSchema() map[string]any {
+	wanted := make(map[string]bool)
+	for _, name := range t.Required {
+		wanted[name] = true
+	}
+	for _, name := range t.Optional {
+		wanted[name] = true
+	}
+
+	var typ reflect.Type
+	if t.input != nil {
+		typ = reflect.TypeOf(t.input)
+	} else {
+		typ = reflect.TypeOf(Action{})
+	}
+
+	properties := make(map[string]any)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !wanted[name] {
+			continue
+		}
+
+		prop := map[string]any{"type": jsonType(field.Type)}
+		if field.Type.Kind() == reflect.Slice {
+			if field.Type.Elem() == reflect.TypeOf(Action{}) {
+				prop["items"] = actionSchema()
+			} else {
+				prop["items"] = map[string]any{"type": jsonType(field.Type.Elem())}
+			}
+		}
+		if doc, ok := fieldDocs[name]; ok {
+			prop["description"] = doc
+		}
+		if enum, ok := fieldEnums[name]; ok {
+			prop["enum"] = enum
+		}
+		properties[name] = prop
+	}
+
+	required := t.Required
+	if required == nil {
+		required = []string{}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// actionSchema returns the schema of an action carried inside another
+// tool's input, such as a remediation: one of the action tools' inputs with
+// the action type added. done and failed are left out.
+func actionSchema() map[string]any {
+	variants := make([]any, 0, len(Tools))
+	for _, t := range Tools {
+		if t.Type == ActionDone || t.Type == ActionFailed {
+			continue
+		}
+		schema := t.InputSchema()
+		schema["properties"].(map[string]any)["type"] = map[string]any{
+			"type":        "string",
+			"enum":        []string{string(t.Type)},
+			"description": t.Description,
+		}
+		schema["required"] = append([]string{"type"}, t.Required...)
+		variants = append(variants, schema)
+	}
+	return map[string]any{
+		"type":  "object",
+		"oneOf": variants,
+	}
+}
+
+// jsonType maps a Go field type to its JSON schema type.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "string"
+	}
+}
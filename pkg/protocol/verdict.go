@@ -0,0 +1,69 @@
+package protocol
+
+// VerdictType is a verifier's decision on an agent's work.
+type VerdictType string
+
+const (
+	VerdictApprove   VerdictType = "approve"
+	VerdictReject    VerdictType = "reject"
+	VerdictRemediate VerdictType = "remediate"
+)
+
+// Verdict is the outcome of a verifier reviewing an agent's work.
+type Verdict struct {
+	Type    VerdictType `json:"type"`
+	Summary string      `json:"summary,omitempty"` // why the work was approved
+	Issues  []string    `json:"issues,omitempty"`
+	Actions []Action    `json:"actions,omitempty"` // fixes for the issues, for remediate
+}
+
+// Validate checks the verdict and the remediation actions it carries.
+func (v *Verdict) Validate() error {
+	switch v.Type {
+	case VerdictApprove:
+		// Summary is optional
+	case VerdictReject:
+		if len(v.Issues) == 0 {
+			return &ValidationError{Field: "issues", Message: "issues are required for reject"}
+		}
+	case VerdictRemediate:
+		if len(v.Actions) == 0 {
+			return &ValidationError{Field: "actions", Message: "actions are required for remediate"}
+		}
+		for i := range v.Actions {
+			act := &v.Actions[i]
+			if act.Type == ActionDone || act.Type == ActionFailed {
+				return &ValidationError{Field: "actions", Message: "remediation actions cannot be " + string(act.Type)}
+			}
+			if err := act.Validate(); err != nil {
+				return &ValidationError{Field: "actions", Message: "remediation action " + string(act.Type) + ": " + err.Error()}
+			}
+		}
+	default:
+		return &ValidationError{Field: "type", Message: "unknown verdict: " + string(v.Type)}
+	}
+	return nil
+}
+
+// VerdictTools lists the verdicts a verifier can give, as tools.
+var VerdictTools = []Tool{
+	{
+		Type:        ActionType(VerdictApprove),
+		Description: "Approve the work: the goal has been achieved correctly.",
+		Optional:    []string{"summary"},
+		input:       Verdict{},
+	},
+	{
+		Type:        ActionType(VerdictReject),
+		Description: "Reject the work, listing the specific problems the agent must fix.",
+		Required:    []string{"issues"},
+		input:       Verdict{},
+	},
+	{
+		Type:        ActionType(VerdictRemediate),
+		Description: "Fix the problems directly by giving the actions to perform, along with the issues they fix.",
+		Required:    []string{"actions"},
+		Optional:    []string{"issues"},
+		input:       Verdict{},
+	},
+}